@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/anchore/go-sync"
+)
+
+// Aggregator accumulates the results of every image in a run; workers record each image and its unknowns
+// concurrently, and the run is reported from a Snapshot once they are done
+type Aggregator struct {
+	lock      sync.Locking
+	files     int
+	packages  int
+	hashTime  time.Duration
+	summaries []imageSummary
+	scanTimes map[string]time.Duration
+	digests   map[string]string
+
+	tasks      *taskHistogram
+	dirs       *pathHistogram
+	extensions *pathHistogram
+	layers     *pathHistogram
+}
+
+func newAggregator(cfg Config) *Aggregator {
+	return &Aggregator{
+		scanTimes:  map[string]time.Duration{},
+		digests:    map[string]string{},
+		tasks:      newTaskHistogram(cfg.Fingerprint),
+		dirs:       newDirHistogram(cfg.DirDepth),
+		extensions: newExtensionHistogram(),
+		layers:     newLayerHistogram(),
+	}
+}
+
+// RecordImage records a scanned image, identified by its scan key, with the time spent hashing its files
+func (a *Aggregator) RecordImage(key string, summary imageSummary, hashTime time.Duration) {
+	defer a.lock.Lock()()
+	a.files += summary.Files
+	a.packages += summary.Packages
+	a.hashTime += hashTime
+	a.summaries = append(a.summaries, summary)
+	a.scanTimes[key] = summary.Duration
+}
+
+// RecordUnknown records the rows of a finished image's unknowns by task and layer, and its distinct files with
+// unknowns by directory and extension
+func (a *Aggregator) RecordUnknown(rows []unknownRow, files []string) {
+	a.tasks.add(rows)
+	a.layers.add(unknownLayers(rows)...)
+	a.dirs.add(files...)
+	a.extensions.add(files...)
+}
+
+// RecordDigest records the digest an image was pinned to
+func (a *Aggregator) RecordDigest(key, digest string) {
+	defer a.lock.Lock()()
+	a.digests[key] = digest
+}
+
+// aggregateSnapshot is a copy of the aggregate results at a point in time
+type aggregateSnapshot struct {
+	Files     int
+	Packages  int
+	HashTime  time.Duration
+	Summaries []imageSummary
+	ScanTimes map[string]time.Duration
+	Digests   map[string]string
+
+	// Tasks, Dirs, Extensions and Layers are all entries of each histogram, ordered by descending count
+	Tasks      []countEntry
+	Dirs       []countEntry
+	Extensions []countEntry
+	Layers     []countEntry
+}
+
+// Snapshot returns a copy of the results recorded so far
+func (a *Aggregator) Snapshot() aggregateSnapshot {
+	defer a.lock.RLock()()
+	return aggregateSnapshot{
+		Files:      a.files,
+		Packages:   a.packages,
+		HashTime:   a.hashTime,
+		Summaries:  slices.Clone(a.summaries),
+		ScanTimes:  maps.Clone(a.scanTimes),
+		Digests:    maps.Clone(a.digests),
+		Tasks:      a.tasks.entries(0),
+		Dirs:       a.dirs.entries(0),
+		Extensions: a.extensions.entries(0),
+		Layers:     a.layers.entries(0),
+	}
+}
+
+// topEntries returns the first limit entries, or none when limit is 0
+func topEntries(entries []countEntry, limit int) []countEntry {
+	return entries[:min(max(limit, 0), len(entries))]
+}
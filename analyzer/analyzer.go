@@ -0,0 +1,436 @@
+// Package analyzer catalogs sources with Syft and reports the unknowns Syft found
+package analyzer
+
+import (
+	"cmp"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+
+	stereoscopeFile "github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/cataloging"
+	"github.com/anchore/syft/syft/cataloging/filecataloging"
+	"github.com/anchore/syft/syft/cataloging/pkgcataloging"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+)
+
+// Filter decides whether an unknown is reported, from the file, the task which reported it and the error message
+type Filter interface {
+	Keep(coord file.Coordinates, task, err string) bool
+}
+
+// SourceGetter gets the source of a reference, as syft.GetSource does
+type SourceGetter func(ctx context.Context, ref string, cfg *syft.GetSourceConfig) (source.Source, error)
+
+// Config holds the parameters for analyzing a single source
+type Config struct {
+	// Sources are the Syft source providers used to get the source, e.g. "registry", "docker" or "dir"
+	Sources []string
+	// RegistryOptions configure pulling from registries, by default the Docker config and credential helpers are
+	// used for authentication
+	RegistryOptions *image.RegistryOptions
+	// Platform selects the platform of multi-arch images, e.g. linux/arm64; empty uses the host platform
+	Platform string
+	// Scope is the cataloging scope, which only applies to image sources
+	Scope source.Scope
+	// Unknowns configures which unknowns Syft reports
+	Unknowns cataloging.UnknownsConfig
+	// Hashers are the digest algorithms Syft's file digest cataloger uses, empty disables Syft file hashing
+	Hashers []crypto.Hash
+	// HashUnknowns computes the sha256 of files with unknowns which Syft did not hash
+	HashUnknowns bool
+	// CatalogerSelection selects the Syft catalogers to run
+	CatalogerSelection pkgcataloging.SelectionRequest
+	// Enrich allows catalogers to use the network to find additional package information, such as licenses
+	Enrich bool
+	// Parallelism is the number of Syft cataloging tasks run concurrently for the source
+	Parallelism int
+	// MaxRetries is the number of times to retry getting the source after a transient error
+	MaxRetries int
+	// Timeout is the maximum time spent analyzing the source, 0 means no limit
+	Timeout time.Duration
+	// MaxImageSize is the largest image in bytes which is cataloged, larger images return ErrImageTooLarge; 0
+	// means no limit
+	MaxImageSize int64
+	// ProfileMemory samples the heap while cataloging to report the peak increase in the Result, which is only
+	// accurate when no other source is analyzed at the same time
+	ProfileMemory bool
+	// Filter, when set, decides which unknowns are reported
+	Filter Filter
+	// MaxErrorsPerFile is the most errors reported for a file once filtered, the rest are replaced by a single
+	// error counting them; 0 means no limit
+	MaxErrorsPerFile int
+	// GetSource gets the source to analyze from the Sources, by default syft.GetSource; tests may provide a fake
+	GetSource SourceGetter
+	// Stream, when set, receives each unknown as it is found, in no particular order, instead of collecting them in
+	// the Result; an error stops the analysis
+	Stream func(Unknown) error
+	// Output, when set, receives human-readable progress, such as retries after transient errors
+	Output io.Writer
+	// SBOM, when set, receives the SBOM of the source once it is cataloged, before its unknowns are reported; an
+	// error stops the analysis
+	SBOM func(*sbom.SBOM) error
+}
+
+// DefaultConfig returns the default analysis parameters for container images
+func DefaultConfig() Config {
+	return Config{
+		Sources: []string{"registry"},
+		Scope:   source.SquashedScope,
+		Unknowns: cataloging.UnknownsConfig{
+			// we are not removing unknowns with packages, to see everything syft reports
+			RemoveWhenPackagesDefined:         false,
+			IncludeExecutablesWithoutPackages: true,
+			IncludeUnexpandedArchives:         true,
+		},
+		HashUnknowns:       true,
+		CatalogerSelection: pkgcataloging.NewSelectionRequest(),
+		Enrich:             true,
+		Parallelism:        1,
+		MaxRetries:         3,
+		GetSource:          syft.GetSource,
+	}
+}
+
+// ErrImageTooLarge is returned for images over the MaxImageSize, which are not cataloged
+var ErrImageTooLarge = errors.New("image too large")
+
+// ErrImageNotFound is returned for images which don't exist, such as a repository without the tag
+var ErrImageNotFound = errors.New("image not found")
+
+// Result is the outcome of analyzing a single source
+type Result struct {
+	Ref       string
+	FileCount int
+	// PackageCount is the number of packages Syft found
+	PackageCount int
+	Duration     time.Duration
+	// Platform is the requested platform, empty when the host platform was used
+	Platform string
+	// Provider is the source provider which provided the source, e.g. "docker"; empty when none did
+	Provider string
+	// PullTime is the time spent getting the source, including pulling the image and any retries
+	PullTime time.Duration
+	// CatalogTime is the time spent creating the SBOM
+	CatalogTime time.Duration
+	// HashTime is the time spent computing the sha256 of files with unknowns
+	HashTime time.Duration
+	// PeakMemory is the approximate peak increase in heap bytes while cataloging, when profiling memory
+	PeakMemory uint64
+	// Unknowns are ordered by file path, with one entry per error; empty when streaming
+	Unknowns []Unknown
+}
+
+// Unknown is a single error Syft reported for a file
+type Unknown struct {
+	Coordinates file.Coordinates
+	// AccessPath is the path the file was accessed by, which differs from Coordinates.RealPath when it was reached
+	// through a link; the real path when no other is known
+	AccessPath string
+	Task       string
+	Error      string
+	// Size, MIME and SHA256 describe the file, any may be empty
+	Size   *int64
+	MIME   string
+	SHA256 string
+	// Class is the kind of file, one of: binary, text, archive, unknown
+	Class string
+	// Kind is the kind of finding, one of: executable, archive, cataloger
+	Kind string
+}
+
+// kinds of unknowns, executables and archives are reported by Syft when no package is found in them, cataloger
+// unknowns are errors reported by catalogers
+const (
+	KindExecutable = "executable"
+	KindArchive    = "archive"
+	KindCataloger  = "cataloger"
+)
+
+// kind classifies an unknown by the messages Syft reports for executables and archives without packages
+func kind(msg string) string {
+	switch msg {
+	case "no package identified in executable file":
+		return KindExecutable
+	case "archive not cataloged", "no package identified in archive":
+		return KindArchive
+	}
+	return KindCataloger
+}
+
+// UnknownFiles returns the number of distinct files with unknowns
+func (r Result) UnknownFiles() int {
+	files := map[file.Coordinates]struct{}{}
+	for _, u := range r.Unknowns {
+		files[u.Coordinates] = struct{}{}
+	}
+	return len(files)
+}
+
+// AnalyzeImage gets the source for ref, catalogs it and returns the unknowns; any panic during analysis is
+// returned as an error
+func AnalyzeImage(ctx context.Context, ref string, cfg Config) (result Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	start := time.Now()
+	result.Ref = ref
+	result.Platform = cfg.Platform
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+		defer func() {
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("timed out after %v: %w", time.Since(start), err)
+			}
+		}()
+	}
+
+	srcCfg := syft.DefaultGetSourceConfig().WithSources(cfg.Sources...)
+	if cfg.RegistryOptions != nil {
+		srcCfg = srcCfg.WithRegistryOptions(cfg.RegistryOptions)
+	}
+	if cfg.Platform != "" {
+		platform, err := image.NewPlatform(cfg.Platform)
+		if err != nil {
+			return result, err
+		}
+		srcCfg = srcCfg.WithPlatform(platform)
+	}
+
+	log := LoggerFrom(ctx)
+	log.Debugf("getting source of %s from: %s", ref, strings.Join(cfg.Sources, ", "))
+	pullStart := time.Now()
+	getSource := cfg.GetSource
+	if getSource == nil {
+		getSource = syft.GetSource
+	}
+	getSource = firstProvider(getSource, cfg.Sources, &result.Provider)
+	src, err := getSourceWithRetry(ctx, getSource, ref, srcCfg, cfg.MaxRetries, cfg.Output)
+	result.PullTime = time.Since(pullStart)
+	if err != nil {
+		if isNotFoundError(err) {
+			return result, fmt.Errorf("%w: %w", ErrImageNotFound, err)
+		}
+		return result, fmt.Errorf("unable to get source: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+	log.Debugf("got source from %s in %v", result.Provider, result.PullTime)
+
+	if cfg.MaxImageSize > 0 {
+		if metadata, ok := src.Describe().Metadata.(source.ImageMetadata); ok && metadata.Size > cfg.MaxImageSize {
+			return result, fmt.Errorf("%w: %v MiB over %v MiB", ErrImageTooLarge, metadata.Size>>20, cfg.MaxImageSize>>20)
+		}
+	}
+
+	resolver, err := src.FileResolver(cfg.Scope)
+	if err != nil {
+		return result, fmt.Errorf("unable to get file resolver: %w", err)
+	}
+	result.FileCount = countFiles(ctx, resolver)
+
+	sbomCfg := syft.DefaultCreateSBOMConfig().
+		WithSearchConfig(cataloging.DefaultSearchConfig().WithScope(cfg.Scope)).
+		WithUnknownsConfig(cfg.Unknowns).
+		// only hash with the configured algorithms, by default this disables file hashing
+		WithFilesConfig(filecataloging.DefaultConfig().WithHashers(cfg.Hashers...)).
+		WithCatalogerSelection(cfg.CatalogerSelection).
+		WithParallelism(cfg.Parallelism)
+
+	if cfg.Enrich {
+		sbomCfg = sbomCfg.WithPackagesConfig(sbomCfg.Packages.
+			WithJavascriptConfig(sbomCfg.Packages.JavaScript.WithSearchRemoteLicenses(true)).
+			WithJavaArchiveConfig(sbomCfg.Packages.JavaArchive.WithUseNetwork(true)).
+			WithGolangConfig(sbomCfg.Packages.Golang.WithSearchRemoteLicenses(true)),
+		)
+	}
+
+	catalogStart := time.Now()
+	stopSampling := func() uint64 { return 0 }
+	if cfg.ProfileMemory {
+		stopSampling = sampleHeap()
+	}
+	s, err := withContext(ctx, func() (*sbom.SBOM, error) {
+		return syft.CreateSBOM(ctx, cancelSource{Source: src, ctx: ctx}, sbomCfg)
+	})
+	result.CatalogTime = time.Since(catalogStart)
+	result.PeakMemory = stopSampling()
+	if err != nil {
+		return result, fmt.Errorf("unable to create SBOM: %w", err)
+	}
+	result.PackageCount = len(s.Artifacts.Packages.Sorted())
+	log.Debugf("cataloged %v files in %v: %v packages, %v files with unknowns", result.FileCount,
+		result.CatalogTime, result.PackageCount, len(s.Artifacts.Unknowns))
+	if cfg.SBOM != nil {
+		if err = cfg.SBOM(s); err != nil {
+			return result, err
+		}
+	}
+
+	details := newDetailsLookup(s, resolver, cfg.HashUnknowns)
+	emit := cfg.Stream
+	if emit == nil {
+		emit = func(u Unknown) error {
+			result.Unknowns = append(result.Unknowns, u)
+			return nil
+		}
+	}
+	err = eachUnknown(s.Artifacts.Unknowns, cfg.Filter, cfg.MaxErrorsPerFile, details, cfg.Stream == nil, emit)
+	result.HashTime = details.hashTime
+	if err != nil {
+		return result, err
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// firstProvider returns a getter trying each of the providers in turn, as Syft does, recording the provider which
+// provided the source so the caller knows where it came from, such as to remove images pulled by the Docker daemon
+func firstProvider(getSource SourceGetter, providers []string, provider *string) SourceGetter {
+	return func(ctx context.Context, ref string, cfg *syft.GetSourceConfig) (source.Source, error) {
+		var errs []error
+		for _, p := range providers {
+			providerCfg := *cfg
+			src, err := getSource(ctx, ref, providerCfg.WithSources(p))
+			if err == nil {
+				*provider = p
+				return src, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, providerErrors(errs)
+	}
+}
+
+// countFiles counts the files the resolver provides without collecting every location in memory; as with a "**/*"
+// glob, directories are excluded and symlinks are not counted separately from the files they link to
+func countFiles(ctx context.Context, resolver file.Resolver) int {
+	count := 0
+	for location := range resolver.AllLocations(ctx) {
+		m, err := resolver.FileMetadataByLocation(location)
+		if err == nil && (m.Type == stereoscopeFile.TypeDirectory || m.Type == stereoscopeFile.TypeSymLink) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// eachUnknown flattens the unknowns to an entry per error, optionally sorted by file path, layer, task and error so
+// unchanged sources produce the same order in every run, dropping errors removed by the filter and those beyond
+// maxErrors for a file
+func eachUnknown(unknownMap map[file.Coordinates][]string, filter Filter, maxErrors int, details *detailsLookup,
+	sorted bool, fn func(Unknown) error) error {
+	keys := maps.Keys(unknownMap)
+	if sorted {
+		slices.SortFunc(keys, func(a, b file.Coordinates) int {
+			return cmp.Or(strings.Compare(a.RealPath, b.RealPath), strings.Compare(a.FileSystemID, b.FileSystemID))
+		})
+	}
+
+	for _, coord := range keys {
+		errs := filterUnknowns(coord, unknownMap[coord], filter)
+		if len(errs) == 0 {
+			continue
+		}
+		if sorted {
+			// the errors of a file are in the order catalogers reported them, which varies between runs
+			slices.SortFunc(errs, compareErrors)
+		}
+		errs = capErrors(errs, maxErrors)
+
+		d := details.lookup(coord)
+		for _, err := range errs {
+			task, msg := splitTask(err)
+			u := Unknown{
+				Coordinates: coord,
+				AccessPath:  d.AccessPath,
+				Task:        task,
+				Error:       msg,
+				Size:        d.Size,
+				MIME:        d.MIME,
+				SHA256:      d.SHA256,
+				Class:       d.Class,
+				Kind:        kind(msg),
+			}
+			if err := fn(u); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// filterUnknowns returns the distinct errors of a file the filter keeps, several catalogers may report the same error
+// for a file
+func filterUnknowns(coord file.Coordinates, errs []string, filter Filter) []string {
+	var out []string
+	for _, err := range errs {
+		if slices.Contains(out, err) {
+			continue
+		}
+		if filter != nil {
+			if task, msg := splitTask(err); !filter.Keep(coord, task, msg) {
+				continue
+			}
+		}
+		out = append(out, err)
+	}
+	return out
+}
+
+// capErrors keeps the first limit errors, replacing the rest with one counting them; limit 0 keeps all
+func capErrors(errs []string, limit int) []string {
+	if limit <= 0 || len(errs) <= limit {
+		return errs
+	}
+	return append(errs[:limit:limit], fmt.Sprintf("... and %v more", len(errs)-limit))
+}
+
+// compareErrors orders errors by task, then message
+func compareErrors(a, b string) int {
+	aTask, aMsg := splitTask(a)
+	bTask, bMsg := splitTask(b)
+	return cmp.Or(strings.Compare(aTask, bTask), strings.Compare(aMsg, bMsg))
+}
+
+// splitTask separates the "<task>: " prefix Syft adds to unknown errors from the message
+func splitTask(err string) (task, msg string) {
+	task, msg, found := strings.Cut(err, ": ")
+	if !found {
+		return "", err
+	}
+	return task, msg
+}
+
+// withContext runs fn and waits for it to complete, returning the context error if the context is done by then so a
+// partial result from a cancelled scan is never used; fn is expected to stop promptly on cancellation, for Syft this
+// is done by cataloging a cancelSource
+func withContext[T any](ctx context.Context, fn func() (T, error)) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			var zero T
+			value, err = zero, ctxErr
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"context"
+	"io"
+
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/source"
+)
+
+// cancelSource is a source whose file resolvers stop finding and reading files once the context is done; Syft's
+// catalogers don't check the context, so this is what stops cataloging promptly when a scan times out or the run is
+// interrupted
+type cancelSource struct {
+	source.Source
+	ctx context.Context
+}
+
+func (s cancelSource) FileResolver(scope source.Scope) (file.Resolver, error) {
+	r, err := s.Source.FileResolver(scope)
+	if err != nil {
+		return nil, err
+	}
+	return cancelResolver{Resolver: r, ctx: s.ctx}, nil
+}
+
+// cancelResolver returns the context error, or finds nothing, once the context is done
+type cancelResolver struct {
+	file.Resolver
+	ctx context.Context
+}
+
+func (r cancelResolver) FileContentsByLocation(location file.Location) (io.ReadCloser, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.Resolver.FileContentsByLocation(location)
+}
+
+func (r cancelResolver) FileMetadataByLocation(location file.Location) (file.Metadata, error) {
+	if err := r.ctx.Err(); err != nil {
+		return file.Metadata{}, err
+	}
+	return r.Resolver.FileMetadataByLocation(location)
+}
+
+func (r cancelResolver) HasPath(path string) bool {
+	return r.ctx.Err() == nil && r.Resolver.HasPath(path)
+}
+
+func (r cancelResolver) FilesByPath(paths ...string) ([]file.Location, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.Resolver.FilesByPath(paths...)
+}
+
+func (r cancelResolver) FilesByGlob(patterns ...string) ([]file.Location, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.Resolver.FilesByGlob(patterns...)
+}
+
+func (r cancelResolver) FilesByMIMEType(types ...string) ([]file.Location, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.Resolver.FilesByMIMEType(types...)
+}
+
+func (r cancelResolver) RelativeFileByPath(location file.Location, path string) *file.Location {
+	if r.ctx.Err() != nil {
+		return nil
+	}
+	return r.Resolver.RelativeFileByPath(location, path)
+}
+
+// AllLocations stops when either the caller's context or the resolver's is done
+func (r cancelResolver) AllLocations(ctx context.Context) <-chan file.Location {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(r.ctx, cancel)
+	locations := r.Resolver.AllLocations(ctx)
+	out := make(chan file.Location)
+	go func() {
+		defer close(out)
+		defer cancel()
+		defer stop()
+		for location := range locations {
+			select {
+			case out <- location:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
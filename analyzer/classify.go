@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// file classes of unknowns
+const (
+	ClassBinary  = "binary"
+	ClassText    = "text"
+	ClassArchive = "archive"
+	ClassUnknown = "unknown"
+)
+
+// sniffSize is the number of leading bytes read to classify a file the MIME type doesn't classify
+const sniffSize = 512
+
+var archiveMIMETypes = []string{
+	"application/gzip",
+	"application/java-archive",
+	"application/vnd.debian.binary-package",
+	"application/vnd.rar",
+	"application/x-7z-compressed",
+	"application/x-archive",
+	"application/x-bzip2",
+	"application/x-compress",
+	"application/x-cpio",
+	"application/x-gzip",
+	"application/x-rar-compressed",
+	"application/x-rpm",
+	"application/x-tar",
+	"application/x-xz",
+	"application/zip",
+	"application/zstd",
+}
+
+var binaryMIMETypes = []string{
+	"application/vnd.microsoft.portable-executable",
+	"application/wasm",
+	"application/x-dosexec",
+	"application/x-elf",
+	"application/x-executable",
+	"application/x-mach-binary",
+	"application/x-object",
+	"application/x-sharedlib",
+}
+
+var textMIMETypes = []string{
+	"application/javascript",
+	"application/json",
+	"application/toml",
+	"application/x-perl",
+	"application/x-php",
+	"application/x-ruby",
+	"application/x-sh",
+	"application/x-shellscript",
+	"application/xml",
+	"application/yaml",
+}
+
+// classify returns the class of a file from its MIME type, sniffing the contents when the MIME type is missing or
+// not specific; executables Syft identified are always binary
+func classify(mime string, executable bool, contents func() (io.ReadCloser, error)) string {
+	mime, _, _ = strings.Cut(mime, ";")
+	switch {
+	case executable:
+		return ClassBinary
+	case slices.Contains(archiveMIMETypes, mime):
+		return ClassArchive
+	case slices.Contains(binaryMIMETypes, mime):
+		return ClassBinary
+	case strings.HasPrefix(mime, "text/") || slices.Contains(textMIMETypes, mime):
+		return ClassText
+	}
+	return sniffClass(contents)
+}
+
+// sniffClass classifies the leading bytes of a file as binary when they contain a NUL or are not UTF-8
+func sniffClass(contents func() (io.ReadCloser, error)) string {
+	if contents == nil {
+		return ClassUnknown
+	}
+	r, err := contents()
+	if err != nil {
+		return ClassUnknown
+	}
+	defer func() { _ = r.Close() }()
+
+	buf := make([]byte, sniffSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		// includes empty files
+		return ClassUnknown
+	}
+	buf = buf[:n]
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return ClassBinary
+	}
+	if n == sniffSize {
+		// a multi-byte rune may be cut off at the end of the sample
+		for i := 0; i < utf8.UTFMax-1 && !utf8.Valid(buf); i++ {
+			buf = buf[:len(buf)-1]
+		}
+	}
+	if !utf8.Valid(buf) {
+		return ClassBinary
+	}
+	return ClassText
+}
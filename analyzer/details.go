@@ -0,0 +1,129 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/sbom"
+)
+
+// fileDetails are the properties of a file with unknowns included in the output, any may be empty
+type fileDetails struct {
+	Size   *int64
+	MIME   string
+	SHA256 string
+	Class  string
+	// AccessPath is the path the file was found by, the real path unless a package location records another
+	AccessPath string
+}
+
+// detailsLookup returns details from the SBOM file catalogs, falling back to the resolver for files not in the
+// catalogs, since unknowns are often not owned by packages and file catalogers only select package-owned files;
+// when hashUnknowns is set, files without a sha256 are hashed and the time taken is added to hashTime
+type detailsLookup struct {
+	sbom         *sbom.SBOM
+	resolver     file.Resolver
+	hashUnknowns bool
+	hashTime     time.Duration
+	// accessPaths are the access paths of package locations which differ from their real path, built on first use
+	accessPaths map[file.Coordinates]string
+}
+
+func newDetailsLookup(s *sbom.SBOM, resolver file.Resolver, hashUnknowns bool) *detailsLookup {
+	return &detailsLookup{sbom: s, resolver: resolver, hashUnknowns: hashUnknowns}
+}
+
+func (l *detailsLookup) lookup(coord file.Coordinates) fileDetails {
+	details := fileDetails{Class: ClassUnknown, AccessPath: l.accessPath(coord)}
+
+	location, ok := resolveLocation(l.resolver, coord)
+	m, inCatalog := l.sbom.Artifacts.FileMetadata[coord]
+	if !inCatalog {
+		if !ok {
+			return details
+		}
+		var err error
+		m, err = l.resolver.FileMetadataByLocation(location)
+		if err != nil {
+			return details
+		}
+	}
+	if m.FileInfo != nil {
+		size := m.Size()
+		details.Size = &size
+	}
+	details.MIME = m.MIMEType
+
+	var contents func() (io.ReadCloser, error)
+	if ok && m.FileInfo != nil && m.Mode().IsRegular() {
+		contents = func() (io.ReadCloser, error) {
+			return l.resolver.FileContentsByLocation(location)
+		}
+	}
+	_, executable := l.sbom.Artifacts.Executables[coord]
+	details.Class = classify(details.MIME, executable, contents)
+
+	for _, digest := range l.sbom.Artifacts.FileDigests[coord] {
+		if digest.Algorithm == "sha256" {
+			details.SHA256 = digest.Value
+		}
+	}
+	if l.hashUnknowns && ok && details.SHA256 == "" && m.FileInfo != nil && m.Mode().IsRegular() {
+		start := time.Now()
+		details.SHA256 = sha256Contents(l.resolver, location)
+		l.hashTime += time.Since(start)
+	}
+	return details
+}
+
+// accessPath returns the path a file was accessed by; Syft reports unknowns by coordinates only, so this is taken
+// from the locations of packages found in the file, such as a package reached through a symlink, falling back to
+// the real path
+func (l *detailsLookup) accessPath(coord file.Coordinates) string {
+	if l.accessPaths == nil {
+		l.accessPaths = map[file.Coordinates]string{}
+		for _, p := range l.sbom.Artifacts.Packages.Sorted() {
+			for _, location := range p.Locations.ToSlice() {
+				if _, ok := l.accessPaths[location.Coordinates]; !ok && location.AccessPath != "" &&
+					location.AccessPath != location.RealPath {
+					l.accessPaths[location.Coordinates] = location.AccessPath
+				}
+			}
+		}
+	}
+	if accessPath, ok := l.accessPaths[coord]; ok {
+		return accessPath
+	}
+	return coord.RealPath
+}
+
+// resolveLocation finds the resolver location for coordinates, which is needed to read metadata and contents
+func resolveLocation(resolver file.Resolver, coord file.Coordinates) (file.Location, bool) {
+	locations, err := resolver.FilesByPath(coord.RealPath)
+	if err != nil || len(locations) == 0 {
+		return file.Location{}, false
+	}
+	for _, location := range locations {
+		if location.Coordinates == coord {
+			return location, true
+		}
+	}
+	return locations[0], true
+}
+
+func sha256Contents(resolver file.Resolver, location file.Location) string {
+	contents, err := resolver.FileContentsByLocation(location)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = contents.Close() }()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, contents); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
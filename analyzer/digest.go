@@ -0,0 +1,38 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/anchore/stereoscope/pkg/image"
+)
+
+// ResolveDigest returns ref pinned to the digest its tag currently points to, as name@sha256:..., so a scan can be
+// repeated against the same image after the tag moves; multi-arch images resolve to the digest of their index.
+// Credentials in opts are used for the registry, otherwise those of the Docker config and credential helpers
+func ResolveDigest(ctx context.Context, ref string, opts *image.RegistryOptions) (string, error) {
+	parsed, err := name.ParseReference(ref)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse reference %q: %w", ref, err)
+	}
+	if digest, ok := parsed.(name.Digest); ok {
+		return digest.String(), nil
+	}
+
+	auth := remote.WithAuthFromKeychain(authn.DefaultKeychain)
+	if opts != nil {
+		if authenticator := opts.Authenticator(parsed.Context().RegistryStr()); authenticator != nil {
+			auth = remote.WithAuth(authenticator)
+		}
+	}
+
+	desc, err := remote.Head(parsed, auth, remote.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve digest of %s: %w", ref, err)
+	}
+	return parsed.Context().Digest(desc.Digest.String()).String(), nil
+}
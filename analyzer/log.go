@@ -0,0 +1,24 @@
+package analyzer
+
+import (
+	"context"
+
+	"github.com/anchore/go-logger"
+	"github.com/anchore/go-logger/adapter/discard"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a context the analysis logs to with the logger, usually one nested with fields identifying the
+// scan; Syft itself logs to its global logger, so its lines do not carry the fields
+func WithLogger(ctx context.Context, log logger.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, log)
+}
+
+// LoggerFrom returns the logger of the context, or one which discards everything when there is none
+func LoggerFrom(ctx context.Context) logger.Logger {
+	if log, ok := ctx.Value(loggerKey{}).(logger.Logger); ok {
+		return log
+	}
+	return discard.New()
+}
@@ -0,0 +1,43 @@
+package analyzer
+
+import (
+	"runtime/metrics"
+	"time"
+)
+
+// heapSampleInterval is how often the heap is sampled while profiling memory
+const heapSampleInterval = 50 * time.Millisecond
+
+// sampleHeap samples the heap in use until the returned func is called, which returns the peak increase over the
+// heap in use when sampling started; this is approximate, since it includes any other work in the process
+func sampleHeap() func() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	read := func() uint64 {
+		metrics.Read(sample)
+		return sample[0].Value.Uint64()
+	}
+
+	start := read()
+	peak := start
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(heapSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				peak = max(peak, read())
+			}
+		}
+	}()
+
+	return func() uint64 {
+		close(done)
+		<-stopped
+		peak = max(peak, read())
+		return peak - start
+	}
+}
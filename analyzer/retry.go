@@ -0,0 +1,135 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/source"
+)
+
+// initialRetryDelay is the delay before the first retry, doubling with each subsequent attempt
+const initialRetryDelay = 2 * time.Second
+
+// permanentErrors are registry error fragments which will not succeed when retried
+var permanentErrors = []string{
+	"manifest unknown",
+	"MANIFEST_UNKNOWN",
+	"NAME_UNKNOWN",
+	"UNAUTHORIZED",
+	"DENIED",
+	"not found",
+}
+
+// notFoundErrors are registry and daemon error fragments for images which don't exist
+var notFoundErrors = []string{
+	"manifest unknown",
+	"MANIFEST_UNKNOWN",
+	"NAME_UNKNOWN",
+	"404 Not Found",
+	"No such image",
+}
+
+// transientErrors are error fragments indicating a network or registry problem which may succeed when retried
+var transientErrors = []string{
+	"connection reset",
+	"connection refused",
+	"timeout",
+	"TLS handshake",
+	"unexpected EOF",
+	"TOOMANYREQUESTS",
+	"429 Too Many Requests",
+	"500 Internal Server Error",
+	"502 Bad Gateway",
+	"503 Service Unavailable",
+	"504 Gateway Timeout",
+}
+
+// getSourceWithRetry calls getSource, retrying transient errors up to maxRetries times with exponential backoff;
+// retries are reported to out when it is set
+func getSourceWithRetry(ctx context.Context, getSource SourceGetter, ref string, cfg *syft.GetSourceConfig, maxRetries int, out io.Writer) (source.Source, error) {
+	delay := initialRetryDelay
+	for attempt := 1; ; attempt++ {
+		src, err := getSource(ctx, ref, cfg)
+		if err == nil {
+			return src, nil
+		}
+		if attempt > maxRetries || ctx.Err() != nil || !isTransientError(err) {
+			return nil, err
+		}
+
+		if out != nil {
+			fmt.Fprintf(out, "retrying %s (attempt %v of %v) in %v: %v\n", ref, attempt, maxRetries, delay, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// providerErrors are the errors of each provider which was tried, kept apart so they are classified separately
+type providerErrors []error
+
+func (e providerErrors) Error() string {
+	return errors.Join(e...).Error()
+}
+
+func (e providerErrors) Unwrap() []error {
+	return e
+}
+
+// isNotFoundError returns true when the image doesn't exist, which requires every provider tried to not find it since
+// one may fail for another reason, such as a Docker daemon which isn't running while the registry doesn't have it
+func isNotFoundError(err error) bool {
+	var errs providerErrors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			if !isNotFoundError(e) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	}
+	msg := err.Error()
+	for _, notFound := range notFoundErrors {
+		if strings.Contains(msg, notFound) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransientError returns true when retrying may succeed, which is when any of the providers tried failed transiently
+func isTransientError(err error) bool {
+	var errs providerErrors
+	if errors.As(err, &errs) {
+		return slices.ContainsFunc(errs, isTransientError)
+	}
+	msg := err.Error()
+	for _, permanent := range permanentErrors {
+		if strings.Contains(msg, permanent) {
+			return false
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	for _, transient := range transientErrors {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
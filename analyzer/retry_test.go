@@ -0,0 +1,34 @@
+package analyzer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProviderErrorClassification(t *testing.T) {
+	notFound := errors.New("MANIFEST_UNKNOWN: manifest unknown")
+	noDaemon := errors.New("Cannot connect to the Docker daemon at unix:///var/run/docker.sock")
+	timeout := errors.New("net/http: TLS handshake timeout")
+
+	tests := []struct {
+		name          string
+		err           error
+		wantNotFound  bool
+		wantTransient bool
+	}{
+		{name: "every provider not found", err: providerErrors{notFound, errors.New("No such image: alpine:latest")}, wantNotFound: true},
+		{name: "one provider not found", err: providerErrors{noDaemon, notFound}},
+		{name: "one provider transient", err: providerErrors{notFound, timeout}, wantTransient: true},
+		{name: "no providers", err: providerErrors{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isNotFoundError(test.err); got != test.wantNotFound {
+				t.Errorf("isNotFoundError() = %v, want %v", got, test.wantNotFound)
+			}
+			if got := isTransientError(test.err); got != test.wantTransient {
+				t.Errorf("isTransientError() = %v, want %v", got, test.wantTransient)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// baselineKey identifies an unknown across runs, by fingerprint when fingerprinting since files with the same error
+// are then considered the same unknown, otherwise by file
+type baselineKey struct {
+	image, task, match string
+}
+
+// baseline holds the unknowns of a previous run, so only new unknowns are reported and those which are no longer
+// present can be listed as fixed
+type baseline struct {
+	byFingerprint bool
+	rows          map[string][]unknownRow
+
+	lock    sync.Mutex
+	current map[string]map[baselineKey]struct{}
+	added   int
+}
+
+// loadBaseline reads the result files of a previous run in dir, or the single result file when dir is a file
+func loadBaseline(dir string, byFingerprint bool) (*baseline, error) {
+	rows, err := readResultDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read baseline: %w", err)
+	}
+	return &baseline{
+		byFingerprint: byFingerprint,
+		rows:          rows,
+		current:       map[string]map[baselineKey]struct{}{},
+	}, nil
+}
+
+// resultFilePrefix is the name prefix result files are recognized by in a result directory
+const resultFilePrefix = "unknowns-"
+
+// readResultDir reads the result files in dir and its subdirectories, or the single result file when dir is a file,
+// returning the rows of each image by scan key
+func readResultDir(dir string) (map[string][]unknownRow, error) {
+	paths := []string{dir}
+	if info, err := os.Stat(dir); err != nil || info.IsDir() {
+		paths = nil
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasPrefix(d.Name(), resultFilePrefix) {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	byImage := map[string][]unknownRow{}
+	for _, path := range paths {
+		rows, err := readResultFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			key := scanKey(row.Image, row.Platform)
+			byImage[key] = append(byImage[key], row)
+		}
+	}
+	return byImage, nil
+}
+
+func (b *baseline) key(row unknownRow) baselineKey {
+	if b.byFingerprint {
+		return baselineKey{image: row.Image, task: row.Task, match: fingerprint(row.Error)}
+	}
+	return baselineKey{image: row.Image, task: row.Task, match: row.File}
+}
+
+// newRows returns the unknowns of a scanned image which are not in the baseline, they are only counted once the image
+// is recorded
+func (b *baseline) newRows(image string, rows []unknownRow) []unknownRow {
+	previous := map[baselineKey]struct{}{}
+	for _, row := range b.rows[image] {
+		previous[b.key(row)] = struct{}{}
+	}
+
+	var out []unknownRow
+	for _, row := range rows {
+		if _, ok := previous[b.key(row)]; !ok {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// record records the unknowns of an image once its results are written, with the number which are new; an image
+// which fails is not recorded, so it isn't counted again when it is retried
+func (b *baseline) record(image string, rows []unknownRow, added int) {
+	current := map[baselineKey]struct{}{}
+	for _, row := range rows {
+		current[b.key(row)] = struct{}{}
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.current[image] = current
+	b.added += added
+}
+
+// newCount returns the number of unknowns not in the baseline
+func (b *baseline) newCount() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.added
+}
+
+// fixedRows returns the baseline unknowns which are not present in the images scanned in this run; images which
+// were not scanned are not included
+func (b *baseline) fixedRows() []unknownRow {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var out []unknownRow
+	for image, current := range sorted(b.current) {
+		for _, row := range b.rows[image] {
+			if _, ok := current[b.key(row)]; !ok {
+				out = append(out, row)
+			}
+		}
+	}
+	return out
+}
+
+// readResultFile reads the rows of a CSV, TSV or JSON result file, which may be gzip compressed; CSV and TSV columns
+// are matched by name since optional columns vary between runs, and the separator is taken from the header
+func readResultFile(path string) ([]unknownRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var in io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", path, err)
+		}
+		defer func() { _ = gz.Close() }()
+		in = gz
+	}
+
+	ext := filepath.Ext(strings.TrimSuffix(path, ".gz"))
+	if ext == ".json" {
+		var rows []unknownRow
+		if err = json.NewDecoder(in).Decode(&rows); err != nil {
+			return nil, fmt.Errorf("unable to decode %s: %w", path, err)
+		}
+		return rows, nil
+	}
+
+	buf := bufio.NewReader(in)
+	var r interface{ Read() ([]string, error) }
+	if ext == ".tsv" {
+		r = &tsvReader{r: buf, separator: headerSeparator(buf, '\t')}
+	} else {
+		cr := csv.NewReader(buf)
+		cr.Comma = headerSeparator(buf, ',')
+		cr.FieldsPerRecord = -1
+		// the header of the original result files is "IMAGE","FILE","TASK",ERROR" with a stray quote
+		cr.LazyQuotes = true
+		r = cr
+	}
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	for i := range header {
+		header[i] = strings.Trim(header[i], `"`)
+	}
+
+	var rows []unknownRow
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", path, err)
+		}
+
+		field := func(name string) string {
+			idx := slices.Index(header, name)
+			if idx < 0 || idx >= len(record) {
+				return ""
+			}
+			return record[idx]
+		}
+		row := unknownRow{
+			Image:       field("IMAGE"),
+			Namespace:   field("NAMESPACE"),
+			Platform:    field("PLATFORM"),
+			File:        field("FILE"),
+			AccessPath:  field("ACCESS_PATH"),
+			Layer:       field("LAYER"),
+			MIME:        field("MIME"),
+			Class:       field("CLASS"),
+			SHA256:      field("SHA256"),
+			Kind:        field("KIND"),
+			Task:        field("TASK"),
+			Error:       field("ERROR"),
+			RunID:       field("RUN_ID"),
+			Fingerprint: field("FINGERPRINT"),
+			Hint:        field("HINT"),
+		}
+		if scannedAt, err := time.Parse(time.RFC3339, field("SCANNED_AT")); err == nil {
+			row.ScannedAt = scannedAt
+		}
+		if size, err := strconv.ParseInt(field("SIZE"), 10, 64); err == nil {
+			row.Size = &size
+		}
+		if count, err := strconv.Atoi(field("COUNT")); err == nil {
+			row.Count = count
+		}
+		row.Digest = field("DIGEST")
+		if pinned, err := strconv.ParseBool(field("PINNED")); err == nil {
+			row.Pinned = &pinned
+		}
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestBaselineCountsRecordedImages(t *testing.T) {
+	kept := unknownRow{Image: "example/app:1", File: "/app.jar", Task: "java-archive-cataloger", Error: "not a valid zip file"}
+	fixed := unknownRow{Image: "example/app:1", File: "/old.jar", Task: "java-archive-cataloger", Error: "not a valid zip file"}
+	added := unknownRow{Image: "example/app:1", File: "/new.jar", Task: "java-archive-cataloger", Error: "not a valid zip file"}
+	b := &baseline{
+		rows:    map[string][]unknownRow{"example/app:1": {kept, fixed}},
+		current: map[string]map[baselineKey]struct{}{},
+	}
+
+	scanned := []unknownRow{kept, added}
+	// an attempt which fails after filtering, before it is recorded, is not counted
+	_ = b.newRows("example/app:1", scanned)
+	newRows := b.newRows("example/app:1", scanned)
+	if len(newRows) != 1 || newRows[0].File != "/new.jar" {
+		t.Fatalf("newRows() = %+v, want /new.jar", newRows)
+	}
+	if got := b.newCount(); got != 0 {
+		t.Errorf("newCount() = %v before the image is recorded, want 0", got)
+	}
+
+	b.record("example/app:1", scanned, len(newRows))
+	if got := b.newCount(); got != 1 {
+		t.Errorf("newCount() = %v, want 1", got)
+	}
+	if got := b.fixedRows(); len(got) != 1 || got[0].File != "/old.jar" {
+		t.Errorf("fixedRows() = %+v, want /old.jar", got)
+	}
+}
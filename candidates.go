@@ -0,0 +1,100 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// candidate is a cluster of unknowns sharing a file extension, top-level directory and error fingerprint; the
+// clusters with the most occurrences are the formats new catalogers would identify the most packages for
+type candidate struct {
+	Extension   string             `json:"extension"`
+	Directory   string             `json:"directory"`
+	Fingerprint string             `json:"fingerprint"`
+	Occurrences int                `json:"occurrences"`
+	Images      int                `json:"images"`
+	Examples    []candidateExample `json:"examples"`
+}
+
+// candidateExample is a file with unknowns in a cluster, at most one is kept for each image
+type candidateExample struct {
+	Image string `json:"image"`
+	Path  string `json:"path"`
+}
+
+type candidateKey struct {
+	extension, directory, fingerprint string
+}
+
+// candidateClusters clusters the rows of each image to suggest candidate catalogers
+type candidateClusters struct {
+	examples int
+	clusters map[candidateKey]*candidate
+}
+
+func newCandidateClusters(examples int) *candidateClusters {
+	return &candidateClusters{examples: examples, clusters: map[candidateKey]*candidate{}}
+}
+
+// add clusters the rows of an image, the rows must have fingerprints
+func (c *candidateClusters) add(image string, rows []unknownRow) {
+	inImage := map[candidateKey]struct{}{}
+	for _, row := range rows {
+		key := candidateKey{
+			extension:   extensionBucket(row.File),
+			directory:   dirBucket(row.File, 1),
+			fingerprint: row.Fingerprint,
+		}
+		cluster, ok := c.clusters[key]
+		if !ok {
+			cluster = &candidate{Extension: key.extension, Directory: key.directory, Fingerprint: key.fingerprint}
+			c.clusters[key] = cluster
+		}
+		cluster.Occurrences += max(row.Count, 1)
+
+		if _, ok = inImage[key]; ok {
+			continue
+		}
+		inImage[key] = struct{}{}
+		cluster.Images++
+		if len(cluster.Examples) < c.examples {
+			cluster.Examples = append(cluster.Examples, candidateExample{Image: image, Path: row.File})
+		}
+	}
+}
+
+// entries returns up to limit clusters ordered by descending occurrences, then by the number of images
+func (c *candidateClusters) entries(limit int) []candidate {
+	out := make([]candidate, 0, len(c.clusters))
+	for _, cluster := range c.clusters {
+		out = append(out, *cluster)
+	}
+	slices.SortFunc(out, func(a, b candidate) int {
+		return cmp.Or(
+			cmp.Compare(b.Occurrences, a.Occurrences),
+			cmp.Compare(b.Images, a.Images),
+			cmp.Compare(a.Extension, b.Extension),
+			cmp.Compare(a.Directory, b.Directory),
+			cmp.Compare(a.Fingerprint, b.Fingerprint),
+		)
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func printCandidates(w io.Writer, candidates []candidate) {
+	if len(candidates) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "candidate catalogers by extension, directory and fingerprint:")
+	for _, c := range candidates {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v in %v images\n", c.Extension, c.Directory, c.Fingerprint, c.Occurrences, c.Images)
+		for _, e := range c.Examples {
+			fmt.Fprintf(w, "\t%v\t%v\n", e.Image, e.Path)
+		}
+	}
+}
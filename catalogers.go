@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/cataloging/pkgcataloging"
+)
+
+// catalogerSelection returns the Syft cataloger selection; with no catalogers configured Syft picks the default
+// catalogers for the source type
+func (c Config) catalogerSelection() pkgcataloging.SelectionRequest {
+	return pkgcataloging.NewSelectionRequest().
+		WithDefaults(c.Catalogers...).
+		WithRemovals(c.ExcludeCatalogers...)
+}
+
+// checkCatalogerSelection catalogs an empty directory with the selection, since Syft only validates cataloger
+// names and tags when creating an SBOM and would otherwise report the error for every image
+func checkCatalogerSelection(selection pkgcataloging.SelectionRequest) error {
+	dir, err := os.MkdirTemp("", "analyzer-catalogers-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	src, err := syft.GetSource(context.Background(), dir, syft.DefaultGetSourceConfig().WithSources("dir"))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	_, err = syft.CreateSBOM(context.Background(), src, syft.DefaultCreateSBOMConfig().
+		WithoutFiles().
+		WithCatalogerSelection(selection))
+	if err != nil {
+		return fmt.Errorf("invalid cataloger selection: %w", err)
+	}
+	return nil
+}
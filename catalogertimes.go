@@ -0,0 +1,139 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wagoodman/go-partybus"
+	"github.com/wagoodman/go-progress"
+
+	"github.com/anchore/syft/syft/event"
+	"github.com/anchore/syft/syft/event/monitor"
+	"github.com/anchore/syft/syft/event/parsers"
+)
+
+// catalogerPollInterval is how often running catalogers are checked for completion, cataloger durations are
+// accurate to about this
+const catalogerPollInterval = 10 * time.Millisecond
+
+// slowestCatalogers is the number of catalogers printed with the most time spent
+const slowestCatalogers = 10
+
+// catalogerTime is the time a package cataloger ran, totaled across the images of a run
+type catalogerTime struct {
+	name  string
+	total time.Duration
+	runs  int
+}
+
+// catalogerRun is a package cataloger task which has started and not yet completed
+type catalogerRun struct {
+	name     string
+	started  time.Time
+	progress progress.Progressable
+}
+
+// catalogerTimer times package catalogers from the cataloger tasks Syft publishes to its bus; the bus is shared by
+// every scan, so when scanning in parallel the times include catalogers of other images competing for the CPU
+type catalogerTimer struct {
+	sub  *partybus.Subscription
+	done chan struct{}
+
+	lock  sync.Mutex
+	times map[string]*catalogerTime
+}
+
+// newCatalogerTimer subscribes to cataloger tasks on the bus until stopped
+func newCatalogerTimer(bus *partybus.Bus) *catalogerTimer {
+	t := &catalogerTimer{
+		sub:   bus.Subscribe(event.CatalogerTaskStarted),
+		done:  make(chan struct{}),
+		times: map[string]*catalogerTime{},
+	}
+	go t.run()
+	return t
+}
+
+func (t *catalogerTimer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(catalogerPollInterval)
+	defer ticker.Stop()
+
+	var running []catalogerRun
+	recordCompleted := func() {
+		running = slices.DeleteFunc(running, func(r catalogerRun) bool {
+			if !progress.IsCompleted(r.progress) {
+				return false
+			}
+			t.record(r.name, time.Since(r.started))
+			return true
+		})
+	}
+	for {
+		select {
+		case e, ok := <-t.sub.Events():
+			if !ok {
+				// catalogers still running are not waited on once the timer is stopped
+				recordCompleted()
+				return
+			}
+			prog, task, err := parsers.ParseCatalogerTaskStarted(e)
+			if err != nil || task.ParentID != monitor.PackageCatalogingTaskID {
+				continue
+			}
+			running = append(running, catalogerRun{name: task.ID, started: time.Now(), progress: prog})
+		case <-ticker.C:
+			recordCompleted()
+		}
+	}
+}
+
+func (t *catalogerTimer) record(name string, d time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	ct := t.times[name]
+	if ct == nil {
+		ct = &catalogerTime{name: name}
+		t.times[name] = ct
+	}
+	ct.total += d
+	ct.runs++
+}
+
+// stop unsubscribes from the bus and waits for the timer to finish
+func (t *catalogerTimer) stop() {
+	_ = t.sub.Unsubscribe()
+	<-t.done
+}
+
+// slowest returns the catalogers with the most total time, at most limit
+func (t *catalogerTimer) slowest(limit int) []catalogerTime {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var out []catalogerTime
+	for _, ct := range t.times {
+		out = append(out, *ct)
+	}
+	slices.SortFunc(out, func(a, b catalogerTime) int {
+		return cmp.Or(cmp.Compare(b.total, a.total), strings.Compare(a.name, b.name))
+	})
+	return out[:min(limit, len(out))]
+}
+
+// print prints the catalogers with the most total time across the run
+func (t *catalogerTimer) print(w io.Writer) {
+	slowest := t.slowest(slowestCatalogers)
+	if len(slowest) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "slowest catalogers, by total time across images:\n")
+	for _, ct := range slowest {
+		fmt.Fprintf(w, "%v\t%v\t%v runs, mean %v\n", ct.name, ct.total.Round(time.Millisecond), ct.runs,
+			(ct.total / time.Duration(ct.runs)).Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const checkpointFile = ".completed"
+
+// checkpoint records images which completed successfully, so an interrupted run can be resumed. Each line of
+// the checkpoint file is an image reference and the name of its result file, separated by a tab; the result
+// file is empty when the image had no unknowns.
+type checkpoint struct {
+	lock      sync.Mutex
+	path      string
+	completed map[string]struct{}
+}
+
+// loadCheckpoint reads the checkpoint in resultDir when resuming, otherwise any existing checkpoint is discarded
+func loadCheckpoint(resultDir string, resume bool) (*checkpoint, error) {
+	c := &checkpoint{
+		path:      filepath.Join(resultDir, checkpointFile),
+		completed: map[string]struct{}{},
+	}
+
+	if !resume {
+		if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("unable to remove checkpoint: %w", err)
+		}
+		return c, nil
+	}
+
+	f, err := os.Open(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read checkpoint: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ref, resultFile, _ := strings.Cut(scanner.Text(), "\t")
+		if ref == "" {
+			continue
+		}
+		// only trust the checkpoint if the result file is still present
+		if resultFile != "" {
+			if !filepath.IsAbs(resultFile) {
+				resultFile = filepath.Join(resultDir, resultFile)
+			}
+			if _, err := os.Stat(resultFile); err != nil {
+				continue
+			}
+		}
+		c.completed[ref] = struct{}{}
+	}
+	return c, scanner.Err()
+}
+
+func (c *checkpoint) isComplete(ref string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_, ok := c.completed[ref]
+	return ok
+}
+
+// markComplete appends the image to the checkpoint file; resultFilePath is empty when no result file was written
+func (c *checkpoint) markComplete(ref, resultFilePath string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	resultFile := ""
+	if resultFilePath != "" {
+		// relative to the result directory, since a single result file may be outside of it
+		resultFile = filepath.Base(resultFilePath)
+		if rel, err := filepath.Rel(filepath.Dir(c.path), resultFilePath); err == nil {
+			resultFile = rel
+		}
+	}
+	// write the entry with a single append and sync it, so a crash loses at most the in-flight images
+	if _, err = fmt.Fprintf(f, "%s\t%s\n", ref, resultFile); err != nil {
+		return err
+	}
+	c.completed[ref] = struct{}{}
+	return f.Sync()
+}
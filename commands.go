@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// command is a subcommand, scan is run when no command is given so existing invocations keep working
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands = []command{
+	{name: "scan", summary: "scan images and write the unknowns Syft reports", run: func(args []string) int {
+		return runCommand(args, parseConfig, analyze)
+	}},
+	{name: "report", summary: "aggregate the unknowns of existing result directories", run: func(args []string) int {
+		return runCommand(args, parseReportConfig, report)
+	}},
+	{name: "diff", summary: "compare the unknowns of two result directories", run: func(args []string) int {
+		return runCommand(args, parseDiffConfig, diff)
+	}},
+	{name: "merge", summary: "combine result directories, such as those of a sharded run", run: func(args []string) int {
+		return runCommand(args, parseMergeConfig, merge)
+	}},
+}
+
+// runCommand parses the arguments and runs the command, returning the exit code: 2 for invalid arguments and 1 when
+// the command fails
+func runCommand[T any](args []string, parse func([]string) (T, error), run func(T) error) int {
+	cfg, err := parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+
+	if err = run(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// findCommand returns the command named by the first argument and the remaining arguments, defaulting to scan
+// when the first argument is a flag or there are no arguments
+func findCommand(args []string) (command, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commands[0], args, nil
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c, args[1:], nil
+		}
+	}
+	return command{}, nil, fmt.Errorf("unknown command: %s", args[0])
+}
+
+func printCommands() {
+	fmt.Fprintf(os.Stderr, "usage: analyzer [command] [flags]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s%s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nscan is run when no command is given, use analyzer <command> -h for the flags of each\n")
+}
@@ -0,0 +1,866 @@
+package main
+
+import (
+	"crypto"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kzantow-anchore/syft-unknown-analyzer/analyzer"
+	"golang.org/x/exp/maps"
+	"gopkg.in/yaml.v3"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/syft/syft/cataloging"
+	"github.com/anchore/syft/syft/source"
+)
+
+// Config holds the parameters for a scan run
+type Config struct {
+	// StartAt is the index of the first image to scan
+	StartAt int `yaml:"startAt" json:"startAt"`
+	// Count is the maximum number of images to scan, 0 means no limit
+	Count int `yaml:"count" json:"count"`
+	// MaxImageSize skips images larger than this many MiB, 0 for no limit
+	MaxImageSize int `yaml:"maxImageSize" json:"maxImageSize"`
+	// ResolveDigests resolves the tag of each image to the digest it points to and scans the digest, so runs are
+	// comparable after tags move
+	ResolveDigests bool `yaml:"resolveDigests" json:"resolveDigests"`
+	// Deadline is the maximum duration of the run, after which no new scans are started and in-flight scans
+	// finish; 0 means no limit
+	Deadline time.Duration `yaml:"deadline" json:"deadline"`
+	// Sample scans a random subset of this many of the images selected by StartAt and Count, 0 scans all of them
+	Sample int `yaml:"sample" json:"sample"`
+	// Seed seeds the random sample so it can be repeated, 0 picks a random seed
+	Seed uint64 `yaml:"seed" json:"seed"`
+	// Parallelism is the number of images scanned concurrently, by default the number of CPUs
+	Parallelism int `yaml:"parallelism" json:"parallelism"`
+	// ImageParallelism is the number of Syft cataloging tasks run concurrently within each image, so a large image
+	// does not hold a worker for as long; up to Parallelism times this many tasks run at once
+	ImageParallelism int `yaml:"imageParallelism" json:"imageParallelism"`
+	// MaxMemory pauses starting new scans while the resident memory of the process is over this many MiB, 0 means
+	// no limit
+	MaxMemory int `yaml:"maxMemory" json:"maxMemory"`
+	// Providers are the Syft source providers used to fetch images, tried in order, e.g. "registry" or "docker"
+	Providers []string `yaml:"providers" json:"providers"`
+	// NoCleanup keeps images pulled into the Docker daemon by the docker provider, instead of removing them after each
+	// scan
+	NoCleanup bool `yaml:"noCleanup" json:"noCleanup"`
+	// ResultDir is the directory result files are written to
+	ResultDir string `yaml:"resultDir" json:"resultDir"`
+	// DateDirs writes the results of each run to a subdirectory of ResultDir named by the run start time, e.g.
+	// results/2024-06-01T12-00-00Z
+	DateDirs bool `yaml:"dateDirs" json:"dateDirs"`
+	// Keep removes the oldest date-stamped run directories beyond this many after a run which was not interrupted,
+	// 0 keeps all
+	Keep int `yaml:"keep" json:"keep"`
+	// FilenameTemplate is a text/template naming each result file relative to ResultDir, without the extension;
+	// it may include directories and use {{.Ref}}, {{.Namespace}}, {{.RunID}} and {{.Date}}
+	FilenameTemplate string `yaml:"filenameTemplate" json:"filenameTemplate"`
+	// SourceType is the kind of source to scan, one of: image, dir, file, oci-archive; non-image sources are read
+	// as paths from Images, ImagesFile or ImagesStdin
+	SourceType string `yaml:"sourceType" json:"sourceType"`
+	// Platforms are the platforms of multi-arch images to scan, e.g. linux/arm64, each is scanned separately;
+	// by default the host platform is scanned
+	Platforms []string `yaml:"platforms" json:"platforms"`
+	// Scope is the image cataloging scope, one of: squashed, all-layers; all-layers inflates file counts since files
+	// in every layer are counted, including those which are overwritten or deleted in later layers
+	Scope string `yaml:"scope" json:"scope"`
+	// SourceRegistry selects the registry images are enumerated from, one of: dockerhub, quay, ghcr
+	SourceRegistry string `yaml:"sourceRegistry" json:"sourceRegistry"`
+	// Namespace is the registry namespace or organization to enumerate images from
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// Namespaces are registry namespaces to enumerate images from in turn, replacing Namespace when set
+	Namespaces []string `yaml:"namespaces" json:"namespaces"`
+	// Tags is the number of most recent tags to scan for each repository, 0 scans only :latest
+	Tags int `yaml:"tags" json:"tags"`
+	// PageSize is the number of repositories in each page listed from Docker Hub
+	PageSize int `yaml:"pageSize" json:"pageSize"`
+	// StartPage is the first page listed from Docker Hub, to resume a listing which stopped part way through
+	StartPage int `yaml:"startPage" json:"startPage"`
+	// StartURL is the URL of the first page listed from Docker Hub, replacing the namespace, page size and start page
+	StartURL string `yaml:"startURL" json:"startURL"`
+	// HTTPTimeout is the maximum time for each request made to list images from the registry
+	HTTPTimeout time.Duration `yaml:"httpTimeout" json:"httpTimeout"`
+	// Include only scans images matching one of these globs, or regular expressions prefixed with "re:"; globs
+	// match the repository name, e.g. python*, or the repository path when they contain a slash, e.g. bitnami/*
+	Include []string `yaml:"include" json:"include"`
+	// Exclude skips images matching any of these patterns, as for Include
+	Exclude []string `yaml:"exclude" json:"exclude"`
+	// Images are image references, or paths for non-image source types, to scan instead of Docker Hub; on the
+	// command line these are the arguments after the flags
+	Images []string `yaml:"images" json:"images"`
+	// ImagesFile is an optional path to a newline-delimited list of image references to scan instead of Docker Hub
+	ImagesFile string `yaml:"imagesFile" json:"imagesFile"`
+	// ImagesStdin reads newline-delimited image references to scan from stdin
+	ImagesStdin bool `yaml:"imagesStdin" json:"imagesStdin"`
+	// DedupInputs scans each image reference only once when it is given, listed or read more than once
+	DedupInputs bool `yaml:"dedupInputs" json:"dedupInputs"`
+	// Format is the result file format, one of: csv, json, tsv
+	Format string `yaml:"format" json:"format"`
+	// Separator is the single character separating the fields of csv and tsv result files, by default a comma for
+	// csv and a tab for tsv
+	Separator string `yaml:"separator" json:"separator"`
+	// Gzip compresses result files, appending .gz to their names
+	Gzip bool `yaml:"gzip" json:"gzip"`
+	// S3Bucket is an optional S3 bucket each result file is uploaded to as soon as it is written, using credentials
+	// from the AWS SDK's default chain
+	S3Bucket string `yaml:"s3Bucket" json:"s3Bucket"`
+	// S3Prefix is prepended to the keys of uploaded result files, which are their paths relative to the ResultDir
+	S3Prefix string `yaml:"s3Prefix" json:"s3Prefix"`
+	// S3DeleteLocal removes result files once they are uploaded; resuming rescans the images they belong to
+	S3DeleteLocal bool `yaml:"s3DeleteLocal" json:"s3DeleteLocal"`
+	// SingleFile is an optional path all unknowns are written to, instead of a result file per image
+	SingleFile string `yaml:"singleFile" json:"singleFile"`
+	// Append adds the rows of this run to an existing single file instead of replacing it, to accumulate results
+	// across runs
+	Append bool `yaml:"append" json:"append"`
+	// Fingerprint adds a normalized error with volatile paths, addresses and numbers replaced, and groups the error
+	// histogram by it
+	Fingerprint bool `yaml:"fingerprint" json:"fingerprint"`
+	// Hints adds guidance for known errors to each row
+	Hints bool `yaml:"hints" json:"hints"`
+	// EmitSBOM writes the SBOM of each image in this format next to its result file, one of: spdx-json,
+	// cyclonedx-json; empty writes no SBOM
+	EmitSBOM string `yaml:"emitSbom" json:"emitSbom"`
+	// Stream writes unknowns to the result file as they are found, unsorted, so memory use does not grow with the
+	// number of unknowns in an image
+	Stream bool `yaml:"stream" json:"stream"`
+	// Dedup collapses result rows of identical files, by sha256, with the same task and error into one row with a
+	// count of the rows collapsed
+	Dedup bool `yaml:"dedup" json:"dedup"`
+	// TopDirs is the number of directories with the most files with unknowns listed after the run, 0 disables the
+	// listing
+	TopDirs int `yaml:"topDirs" json:"topDirs"`
+	// DirDepth groups files with unknowns by their first DirDepth path segments, e.g. 2 groups by /usr/lib; 0 groups
+	// by parent directory
+	DirDepth int `yaml:"dirDepth" json:"dirDepth"`
+	// TopExtensions is the number of file extensions with the most files with unknowns listed after the run, 0
+	// disables the listing
+	TopExtensions int `yaml:"topExtensions" json:"topExtensions"`
+	// TopLayers is the number of layers with the most unknowns listed after the run when cataloging all layers, 0
+	// disables the listing
+	TopLayers int `yaml:"topLayers" json:"topLayers"`
+	// RatioThreshold lists the images with a ratio of files with unknowns to files over it after the run, 0
+	// disables the listing
+	RatioThreshold float64 `yaml:"ratioThreshold" json:"ratioThreshold"`
+	// ListClean lists the images scanned without unknowns after the run, and in run.json
+	ListClean bool `yaml:"listClean" json:"listClean"`
+	// SummaryJSON writes summary.json in addition to summary.csv
+	SummaryJSON bool `yaml:"summaryJson" json:"summaryJson"`
+	// EventsJSON writes progress events to stdout as newline-delimited JSON, other output is written to stderr
+	EventsJSON bool `yaml:"eventsJson" json:"eventsJson"`
+	// RunID identifies the run in result rows and run.json, a new one is generated for every run
+	RunID string `yaml:"-" json:"-"`
+	// Events receives progress events, by default they are printed as text to the Output
+	Events EventHandler `yaml:"-" json:"-"`
+	// Output receives human-readable progress and the end of run summary, by default stdout, or stderr with
+	// EventsJSON so stdout only has events
+	Output io.Writer `yaml:"-" json:"-"`
+	// ImageSource yields the references to scan, by default from the configured images, file, stdin or registry;
+	// tests may provide a fixed list
+	ImageSource ImageSource `yaml:"-" json:"-"`
+	// GetSource gets the Syft source of each reference from the Providers, by default syft.GetSource; tests may
+	// provide a fake source
+	GetSource analyzer.SourceGetter `yaml:"-" json:"-"`
+	// MetricsAddr is an optional address to serve Prometheus metrics on, e.g. ":9090"
+	MetricsAddr string `yaml:"metricsAddr" json:"metricsAddr"`
+	// LogFile is an optional path the debug log is also written to, replacing any earlier log
+	LogFile string `yaml:"logFile" json:"logFile"`
+	// LogFormat is the format of the log file, one of: text, json; the console is always text
+	LogFormat string `yaml:"logFormat" json:"logFormat"`
+	// CPUProfile, MemProfile and Trace are optional paths to write a CPU profile, heap profile and execution trace
+	// of the run to
+	CPUProfile string `yaml:"cpuProfile" json:"cpuProfile"`
+	MemProfile string `yaml:"memProfile" json:"memProfile"`
+	Trace      string `yaml:"trace" json:"trace"`
+	// ProfileMem records the approximate peak memory of cataloging each image in the summary, scanning one image at
+	// a time so other scans don't skew it
+	ProfileMem bool `yaml:"profileMem" json:"profileMem"`
+	// ProfileCatalogers records the time each package cataloger runs across the images of the run, printing the
+	// slowest catalogers
+	ProfileCatalogers bool `yaml:"profileCatalogers" json:"profileCatalogers"`
+	// Baseline is an optional result directory, or single result file, of a previous run; only unknowns which are not
+	// in the baseline are reported and those no longer present are written to fixed.csv
+	Baseline string `yaml:"baseline" json:"baseline"`
+	// Since is an optional result directory, or single result file, of a previous run; the unknowns of each image
+	// are compared to it and the added and removed pairs of task and file are written to the image's delta file
+	Since string `yaml:"since" json:"since"`
+	// RegressionThreshold fails the run when there are more new unknowns than the baseline, -1 disables the check
+	RegressionThreshold int `yaml:"regressionThreshold" json:"regressionThreshold"`
+	// DB is an optional path to a SQLite database to insert unknowns into
+	DB string `yaml:"db" json:"db"`
+	// Resume skips images recorded as completed by a previous run in the result directory
+	Resume bool `yaml:"resume" json:"resume"`
+	// SkipExisting skips images with a non-empty result file in the result directory, without a checkpoint; images
+	// without unknowns have no result file, so they are scanned again
+	SkipExisting bool `yaml:"skipExisting" json:"skipExisting"`
+	// RetryFailures scans each failed image once more after all images have been scanned
+	RetryFailures bool `yaml:"retryFailures" json:"retryFailures"`
+	// ImageTimeout is the maximum time spent scanning a single image
+	ImageTimeout time.Duration `yaml:"imageTimeout" json:"imageTimeout"`
+	// FilterFile is an optional path to a list of error substrings, or regular expressions prefixed with "re:",
+	// to exclude from results; by default ELF feature errors are excluded
+	FilterFile string `yaml:"filterFile" json:"filterFile"`
+	// PathInclude only reports unknowns of files whose real path matches one of these globs, with the semantics of
+	// Syft's FilesByGlob, e.g. /usr/lib/**
+	PathInclude []string `yaml:"pathInclude" json:"pathInclude"`
+	// PathExclude drops unknowns of files whose real path matches any of these globs, e.g. **/test/**
+	PathExclude []string `yaml:"pathExclude" json:"pathExclude"`
+	// Filters are applied in order after the filter file patterns, an unknown is reported only when every filter
+	// keeps it
+	Filters []FilterConfig `yaml:"filters" json:"filters"`
+	// MaxRetries is the number of times to retry fetching an image after a transient error
+	MaxRetries int `yaml:"maxRetries" json:"maxRetries"`
+	// MaxFailures aborts the run once this many images have failed, letting in-flight scans finish; 0 means no limit
+	MaxFailures int `yaml:"maxFailures" json:"maxFailures"`
+	// MaxErrorsPerFile is the most errors reported for each file, the rest are replaced by one row counting them; 0
+	// means no limit
+	MaxErrorsPerFile int `yaml:"maxErrorsPerFile" json:"maxErrorsPerFile"`
+	// Hashers are the digest algorithms Syft's file digest cataloger uses, by default file hashing is disabled
+	Hashers []string `yaml:"hashers" json:"hashers"`
+	// NoHash disables all hashing, including the sha256 of files with unknowns
+	NoHash bool `yaml:"noHash" json:"noHash"`
+	// Catalogers are the Syft cataloger names or tags to run instead of the defaults for the source type
+	Catalogers []string `yaml:"catalogers" json:"catalogers"`
+	// ExcludeCatalogers are Syft cataloger names or tags removed from the selected catalogers
+	ExcludeCatalogers []string `yaml:"excludeCatalogers" json:"excludeCatalogers"`
+	// Unknowns configures which unknowns Syft reports
+	Unknowns UnknownsConfig `yaml:"unknowns" json:"unknowns"`
+	// Registries are the credentials used to pull from private registries, registries without credentials use the
+	// Docker config (~/.docker/config.json) and credential helpers
+	Registries []RegistryCredentials `yaml:"registries" json:"registries"`
+}
+
+// RegistryCredentials authenticate with a registry using a username and password, or a token
+type RegistryCredentials struct {
+	// Authority is the registry the credentials are used for, e.g. ghcr.io; empty uses them for all registries
+	Authority string `yaml:"authority" json:"authority"`
+	Username  string `yaml:"username" json:"username"`
+	Password  string `yaml:"password" json:"password"`
+	Token     string `yaml:"token" json:"token"`
+}
+
+// String describes the credentials with the secrets masked, so they are never logged
+func (r RegistryCredentials) String() string {
+	authority := r.Authority
+	if authority == "" {
+		authority = "all registries"
+	}
+	if r.Token != "" {
+		return fmt.Sprintf("%s: token ******", authority)
+	}
+	return fmt.Sprintf("%s: %s ******", authority, r.Username)
+}
+
+// FilterConfig is one filter of the unknown filter chain, exactly one field is set
+type FilterConfig struct {
+	// DropError drops errors containing a substring, or matching a regular expression prefixed with "re:"
+	DropError string `yaml:"dropError" json:"dropError"`
+	// DropTask drops errors reported by a task, such as a cataloger
+	DropTask string `yaml:"dropTask" json:"dropTask"`
+	// KeepExtensions drops files without one of the extensions
+	KeepExtensions []string `yaml:"keepExtensions" json:"keepExtensions"`
+}
+
+// set returns the number of fields which are set
+func (f FilterConfig) set() int {
+	n := 0
+	for _, set := range []bool{f.DropError != "", f.DropTask != "", len(f.KeepExtensions) > 0} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// UnknownsConfig mirrors cataloging.UnknownsConfig with serialization tags
+type UnknownsConfig struct {
+	RemoveWhenPackagesDefined         bool `yaml:"removeWhenPackagesDefined" json:"removeWhenPackagesDefined"`
+	IncludeExecutablesWithoutPackages bool `yaml:"includeExecutablesWithoutPackages" json:"includeExecutablesWithoutPackages"`
+	IncludeUnexpandedArchives         bool `yaml:"includeUnexpandedArchives" json:"includeUnexpandedArchives"`
+}
+
+// DefaultConfig returns the default scan parameters
+func DefaultConfig() Config {
+	defaults := analyzer.DefaultConfig()
+	return Config{
+		StartAt:             0,
+		Count:               1000,
+		Parallelism:         runtime.NumCPU(),
+		ImageParallelism:    1,
+		DedupInputs:         true,
+		Providers:           []string{"registry"}, // or "docker", etc.
+		ResultDir:           "results",
+		FilenameTemplate:    defaultFilenameTemplate,
+		Format:              "csv",
+		LogFormat:           "text",
+		MaxRetries:          defaults.MaxRetries,
+		ImageTimeout:        10 * time.Minute,
+		SourceType:          "image",
+		Scope:               source.SquashedScope.String(),
+		SourceRegistry:      "dockerhub",
+		Namespace:           "library",
+		HTTPTimeout:         30 * time.Second,
+		PageSize:            dockerHubMaxPageSize,
+		StartPage:           1,
+		Output:              os.Stdout,
+		ImageSource:         imagesIterator,
+		GetSource:           defaults.GetSource,
+		RegressionThreshold: -1,
+		TopDirs:             10,
+		TopExtensions:       10,
+		TopLayers:           10,
+		Unknowns: UnknownsConfig{
+			RemoveWhenPackagesDefined:         defaults.Unknowns.RemoveWhenPackagesDefined,
+			IncludeExecutablesWithoutPackages: defaults.Unknowns.IncludeExecutablesWithoutPackages,
+			IncludeUnexpandedArchives:         defaults.Unknowns.IncludeUnexpandedArchives,
+		},
+	}
+}
+
+func (c UnknownsConfig) toSyft() cataloging.UnknownsConfig {
+	return cataloging.UnknownsConfig{
+		RemoveWhenPackagesDefined:         c.RemoveWhenPackagesDefined,
+		IncludeExecutablesWithoutPackages: c.IncludeExecutablesWithoutPackages,
+		IncludeUnexpandedArchives:         c.IncludeUnexpandedArchives,
+	}
+}
+
+// parseConfig parses command-line arguments and an optional config file into a validated Config;
+// flags explicitly provided on the command line take precedence over values from the config file
+func parseConfig(args []string) (Config, error) {
+	cfg := DefaultConfig()
+	configFile := ""
+	registry := RegistryCredentials{}
+
+	hashNames := maps.Keys(hashAlgorithms)
+	slices.Sort(hashNames)
+
+	fs := flag.NewFlagSet("analyzer scan", flag.ContinueOnError)
+	fs.StringVar(&configFile, "config", configFile, "path to a YAML or JSON config file")
+	fs.IntVar(&cfg.StartAt, "start", cfg.StartAt, "index of the first image to scan")
+	fs.IntVar(&cfg.MaxImageSize, "max-image-size", cfg.MaxImageSize, "skip images larger than this many MiB, 0 for no limit")
+	fs.BoolVar(&cfg.ResolveDigests, "resolve-digests", cfg.ResolveDigests, "scan the digest each image tag points to, recording it in a DIGEST column and run.json")
+	fs.DurationVar(&cfg.Deadline, "deadline", cfg.Deadline, "stop starting new scans after this long, e.g. 2h, letting in-flight scans finish, 0 for no limit")
+	fs.IntVar(&cfg.Sample, "sample", cfg.Sample, "scan a random sample of this many images, 0 to scan all images")
+	fs.Uint64Var(&cfg.Seed, "seed", cfg.Seed, "seed of the random sample, 0 picks a random seed which is recorded in run.json")
+	fs.IntVar(&cfg.Count, "count", cfg.Count, "maximum number of images to scan, 0 for no limit")
+	fs.IntVar(&cfg.Parallelism, "parallelism", cfg.Parallelism, "number of images to scan concurrently")
+	fs.IntVar(&cfg.ImageParallelism, "image-parallelism", cfg.ImageParallelism, "number of syft cataloging tasks to run concurrently within each image")
+	fs.IntVar(&cfg.MaxMemory, "max-memory", cfg.MaxMemory, "pause starting new scans while resident memory is over this many MiB, 0 for no limit")
+	fs.StringVar(&cfg.SourceType, "source-type", cfg.SourceType, "kind of source to scan: "+strings.Join(sourceTypes, ", "))
+	fs.Var((*stringList)(&cfg.Providers), "providers", "comma-separated Syft source providers of images, tried in order, e.g. registry,docker")
+	fs.Var((*stringList)(&cfg.Platforms), "platform", "comma-separated platforms of multi-arch images to scan, e.g. linux/amd64,linux/arm64")
+	fs.StringVar(&cfg.Scope, "scope", cfg.Scope, "image cataloging scope: squashed, or all-layers which inflates file counts and reports the layer of each unknown")
+	fs.StringVar(&cfg.SourceRegistry, "source-registry", cfg.SourceRegistry, "registry to enumerate images from: "+strings.Join(sourceRegistries, ", "))
+	fs.StringVar(&cfg.Namespace, "namespace", cfg.Namespace, "registry namespace or organization to enumerate images from")
+	fs.Var((*stringList)(&cfg.Include), "include", "comma-separated globs, or re:<regex> patterns, of image names to scan, e.g. python*")
+	fs.Var((*stringList)(&cfg.Exclude), "exclude", "comma-separated globs, or re:<regex> patterns, of image names to skip")
+	fs.Var((*stringList)(&cfg.Namespaces), "namespaces", "comma-separated registry namespaces to enumerate images from, e.g. library,bitnami")
+	fs.IntVar(&cfg.Tags, "tags", cfg.Tags, "number of most recent tags to scan for each repository, 0 for only :latest")
+	fs.IntVar(&cfg.PageSize, "page-size", cfg.PageSize, "number of repositories in each page listed from Docker Hub")
+	fs.IntVar(&cfg.StartPage, "start-page", cfg.StartPage, "first page to list from Docker Hub, to resume a listing")
+	fs.StringVar(&cfg.StartURL, "start-url", cfg.StartURL, "URL of the first page to list from Docker Hub, e.g. the next page reported when listing stopped")
+	fs.DurationVar(&cfg.HTTPTimeout, "http-timeout", cfg.HTTPTimeout, "maximum time for each request listing images from the registry")
+	fs.StringVar(&cfg.ImagesFile, "images-file", cfg.ImagesFile, "path to a newline-delimited list of image references to scan")
+	fs.StringVar(&cfg.Format, "format", cfg.Format, "result file format: "+strings.Join(resultFormats, ", "))
+	fs.StringVar(&cfg.Separator, "separator", cfg.Separator, "field separator of csv and tsv result files, a comma for csv and a tab for tsv by default")
+	fs.StringVar(&cfg.ResultDir, "out-dir", cfg.ResultDir, "directory to write result files to, created if it does not exist")
+	fs.BoolVar(&cfg.DateDirs, "date-dirs", cfg.DateDirs, "write each run's results to a subdirectory of the out-dir named by the run start time")
+	fs.IntVar(&cfg.Keep, "keep", cfg.Keep, "remove the oldest run directories beyond this many after a run which was not interrupted, requires date-dirs; 0 keeps all")
+	fs.StringVar(&cfg.FilenameTemplate, "filename-template", cfg.FilenameTemplate, "template of result file names in the out-dir, without the extension, using {{.Ref}}, {{.Namespace}}, {{.RunID}} and {{.Date}}; may include directories")
+	fs.BoolVar(&cfg.Fingerprint, "fingerprint", cfg.Fingerprint, "add a FINGERPRINT column of normalized errors and group the error histogram by it")
+	fs.BoolVar(&cfg.Hints, "hints", cfg.Hints, "add a HINT column with guidance for known errors")
+	fs.StringVar(&cfg.EmitSBOM, "emit-sbom", cfg.EmitSBOM, "also write the SBOM of each image in a format: "+strings.Join(sbomFormats, ", "))
+	fs.BoolVar(&cfg.NoCleanup, "no-cleanup", cfg.NoCleanup, "keep images pulled by the docker provider instead of removing them after each scan")
+	fs.BoolVar(&cfg.Gzip, "gzip", cfg.Gzip, "gzip compress result files, appending .gz to their names")
+	fs.StringVar(&cfg.S3Bucket, "s3-bucket", cfg.S3Bucket, "S3 bucket to upload each result file to as it is written, credentials are found by the AWS SDK's default chain")
+	fs.StringVar(&cfg.S3Prefix, "s3-prefix", cfg.S3Prefix, "prefix of uploaded result files in the S3 bucket, followed by their path relative to the result directory")
+	fs.BoolVar(&cfg.S3DeleteLocal, "s3-delete-local", cfg.S3DeleteLocal, "remove result files after they are uploaded to S3")
+	fs.StringVar(&cfg.SingleFile, "single-file", cfg.SingleFile, "write all unknowns to this file instead of a result file per image, e.g. results/all-unknowns.csv")
+	fs.BoolVar(&cfg.Append, "append", cfg.Append, "append to an existing single-file instead of replacing it")
+	fs.BoolVar(&cfg.Stream, "stream", cfg.Stream, "write unknowns unsorted as they are found, bounding memory for images with many unknowns")
+	fs.BoolVar(&cfg.Dedup, "dedup", cfg.Dedup, "collapse rows of identical files with the same task and error, adding a COUNT column")
+	fs.IntVar(&cfg.TopDirs, "top-dirs", cfg.TopDirs, "number of directories with the most files with unknowns to list, 0 to disable")
+	fs.IntVar(&cfg.DirDepth, "dir-depth", cfg.DirDepth, "group files with unknowns by their first N path segments, 0 for the parent directory")
+	fs.IntVar(&cfg.TopExtensions, "top-extensions", cfg.TopExtensions, "number of file extensions with the most files with unknowns to list, 0 to disable")
+	fs.IntVar(&cfg.TopLayers, "top-layers", cfg.TopLayers, "number of layers with the most unknowns to list when cataloging all layers, 0 to disable")
+	fs.Float64Var(&cfg.RatioThreshold, "ratio-threshold", cfg.RatioThreshold, "list images with a ratio of files with unknowns to files over this, 0 to disable")
+	fs.BoolVar(&cfg.ListClean, "list-clean", cfg.ListClean, "list the images scanned without unknowns")
+	fs.BoolVar(&cfg.SummaryJSON, "summary-json", cfg.SummaryJSON, "write summary.json in addition to summary.csv")
+	fs.BoolVar(&cfg.EventsJSON, "events-json", cfg.EventsJSON, "write progress events to stdout as newline-delimited JSON, other output is written to stderr")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", cfg.MetricsAddr, "address to serve Prometheus metrics on /metrics, e.g. :9090")
+	fs.StringVar(&cfg.LogFile, "log-file", cfg.LogFile, "path to also write the debug log to")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "log file format: "+strings.Join(logFormats, ", "))
+	fs.StringVar(&cfg.CPUProfile, "cpuprofile", cfg.CPUProfile, "path to write a CPU profile of the run to")
+	fs.StringVar(&cfg.MemProfile, "memprofile", cfg.MemProfile, "path to write a heap profile to once the scans are done")
+	fs.StringVar(&cfg.Trace, "trace", cfg.Trace, "path to write an execution trace of the run to")
+	fs.BoolVar(&cfg.ProfileMem, "profile-mem", cfg.ProfileMem, "record the approximate peak memory of cataloging each image in the summary, scanning one image at a time")
+	fs.BoolVar(&cfg.ProfileCatalogers, "profile-catalogers", cfg.ProfileCatalogers, "record the time each package cataloger runs and print the slowest catalogers")
+	fs.StringVar(&cfg.Baseline, "baseline", cfg.Baseline, "result directory or single result file of a previous run, only unknowns not in it are reported")
+	fs.StringVar(&cfg.Since, "since", cfg.Since, "result directory or single result file of a previous run to write the change in each image's unknowns since")
+	fs.IntVar(&cfg.RegressionThreshold, "regression-threshold", cfg.RegressionThreshold, "fail when there are more new unknowns than the baseline, -1 to disable")
+	fs.StringVar(&cfg.DB, "db", cfg.DB, "path to a SQLite database to insert unknowns into")
+	fs.IntVar(&cfg.MaxRetries, "max-retries", cfg.MaxRetries, "number of times to retry fetching an image after a transient error")
+	fs.IntVar(&cfg.MaxFailures, "max-failures", cfg.MaxFailures, "stop starting new scans once this many images have failed, 0 for no limit")
+	fs.IntVar(&cfg.MaxErrorsPerFile, "max-errors-per-file", cfg.MaxErrorsPerFile, "most errors reported for each file, the rest are replaced by an \"... and N more\" row; 0 for no limit")
+	fs.BoolVar(&cfg.RetryFailures, "retry-failures", cfg.RetryFailures, "scan each failed image once more after all images have been scanned")
+	fs.DurationVar(&cfg.ImageTimeout, "image-timeout", cfg.ImageTimeout, "maximum time spent scanning a single image")
+	fs.BoolVar(&cfg.Unknowns.RemoveWhenPackagesDefined, "remove-when-packaged", cfg.Unknowns.RemoveWhenPackagesDefined, "remove unknowns for files which have packages defined")
+	fs.BoolVar(&cfg.Unknowns.IncludeExecutablesWithoutPackages, "include-executables", cfg.Unknowns.IncludeExecutablesWithoutPackages, "include executables without packages as unknowns")
+	fs.BoolVar(&cfg.Unknowns.IncludeUnexpandedArchives, "include-archives", cfg.Unknowns.IncludeUnexpandedArchives, "include archives which were not expanded as unknowns")
+	fs.Var((*stringList)(&cfg.Hashers), "hash", "comma-separated digest algorithms for Syft file hashing: "+strings.Join(hashNames, ", "))
+	fs.BoolVar(&cfg.NoHash, "no-hash", cfg.NoHash, "disable all file hashing, including the sha256 of files with unknowns")
+	fs.Var((*stringList)(&cfg.Catalogers), "catalogers", "comma-separated Syft cataloger names or tags to run instead of the defaults")
+	fs.Var((*stringList)(&cfg.ExcludeCatalogers), "exclude-catalogers", "comma-separated Syft cataloger names or tags to exclude")
+	fs.StringVar(&cfg.FilterFile, "filter-file", cfg.FilterFile, "path to a list of error substrings or re:<regex> patterns to exclude")
+	fs.Var((*stringList)(&cfg.PathInclude), "path-include", "comma-separated globs of file paths to report unknowns for, e.g. /usr/lib/**")
+	fs.Var((*stringList)(&cfg.PathExclude), "path-exclude", "comma-separated globs of file paths to drop unknowns for, e.g. **/test/**")
+	fs.StringVar(&registry.Authority, "registry-authority", registry.Authority, "registry the credentials are used for, e.g. ghcr.io, by default all registries")
+	fs.StringVar(&registry.Username, "registry-username", registry.Username, "username for private registries, or set REGISTRY_USERNAME")
+	fs.StringVar(&registry.Password, "registry-password", registry.Password, "password for private registries, or set REGISTRY_PASSWORD")
+	fs.BoolVar(&cfg.Resume, "resume", cfg.Resume, "skip images completed by a previous run")
+	fs.BoolVar(&cfg.SkipExisting, "skip-existing", cfg.SkipExisting, "skip images which already have a non-empty result file in the result directory")
+	fs.BoolVar(&cfg.ImagesStdin, "images-stdin", cfg.ImagesStdin, "read newline-delimited image references to scan from stdin")
+	fs.BoolVar(&cfg.DedupInputs, "dedup-inputs", cfg.DedupInputs, "scan each image reference once, dropping duplicates from the input with a warning")
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: analyzer scan [flags] [image...]\n")
+		fs.PrintDefaults()
+	}
+	images, err := parseInterspersed(fs, args)
+	if err != nil {
+		return cfg, err
+	}
+
+	if configFile != "" {
+		// remember the explicitly set flags, so they can be re-applied over the file values
+		explicit := map[string]string{}
+		fs.Visit(func(f *flag.Flag) {
+			explicit[f.Name] = f.Value.String()
+		})
+
+		if err := loadConfigFile(configFile, &cfg); err != nil {
+			return cfg, err
+		}
+
+		for name, value := range explicit {
+			if err := fs.Set(name, value); err != nil {
+				return cfg, err
+			}
+		}
+	}
+
+	// images given as arguments replace those in the config file
+	if len(images) > 0 {
+		cfg.Images = images
+	}
+
+	// the environment allows passwords to be provided without appearing in the process list or shell history
+	if registry.Username == "" {
+		registry.Username = os.Getenv("REGISTRY_USERNAME")
+	}
+	if registry.Password == "" {
+		registry.Password = os.Getenv("REGISTRY_PASSWORD")
+	}
+	if registry.Username != "" || registry.Password != "" {
+		cfg.Registries = append(cfg.Registries, registry)
+	}
+
+	if cfg.Sample > 0 && cfg.Seed == 0 {
+		cfg.Seed = rand.Uint64()
+	}
+
+	return cfg, cfg.validate()
+}
+
+// loadConfigFile reads a YAML file into the provided Config, since JSON is a subset of YAML this handles both
+func loadConfigFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open config file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err = dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("unable to parse config file %q: %w", path, err)
+	}
+	return nil
+}
+
+var sourceTypes = []string{"image", "dir", "file", "oci-archive"}
+
+// sourceProviders returns the Syft source providers used to get each source
+func (c Config) sourceProviders() []string {
+	if c.SourceType == "image" {
+		return c.Providers
+	}
+	// the non-image source types are named the same as their Syft providers
+	return []string{c.SourceType}
+}
+
+// scope returns the scope used to resolve files, which only applies to image sources
+func (c Config) scope() source.Scope {
+	switch c.SourceType {
+	case "image", "oci-archive":
+		return source.ParseScope(c.Scope)
+	default:
+		// directory and file sources have a single filesystem, so ignore the scope
+		return source.UnknownScope
+	}
+}
+
+// platforms returns the platforms each image is scanned for, a single empty platform scans the host platform
+func (c Config) platforms() []string {
+	if len(c.Platforms) == 0 {
+		return []string{""}
+	}
+	return c.Platforms
+}
+
+// listsImages returns true when images are enumerated from the registry, rather than given as arguments or read
+// from a file or stdin
+func (c Config) listsImages() bool {
+	return len(c.Images) == 0 && c.ImagesFile == "" && !c.ImagesStdin
+}
+
+// namespaces returns the registry namespaces images are enumerated from
+func (c Config) namespaces() []string {
+	if len(c.Namespaces) == 0 {
+		return []string{c.Namespace}
+	}
+	return c.Namespaces
+}
+
+// scanKey identifies a scan of an image on a platform in the checkpoint, failures and result filenames
+func scanKey(ref, platform string) string {
+	if platform == "" {
+		return ref
+	}
+	return ref + " " + platform
+}
+
+// analyzerConfig returns the parameters used to analyze each image
+func (c Config) analyzerConfig(filter analyzer.Filter) (analyzer.Config, error) {
+	hashers, err := c.hashers()
+	if err != nil {
+		return analyzer.Config{}, err
+	}
+
+	cfg := analyzer.DefaultConfig()
+	cfg.Sources = c.sourceProviders()
+	cfg.RegistryOptions = c.registryOptions()
+	cfg.Scope = c.scope()
+	cfg.Unknowns = c.Unknowns.toSyft()
+	cfg.Hashers = hashers
+	cfg.HashUnknowns = !c.NoHash
+	cfg.CatalogerSelection = c.catalogerSelection()
+	cfg.Parallelism = c.ImageParallelism
+	cfg.MaxRetries = c.MaxRetries
+	cfg.Timeout = c.ImageTimeout
+	cfg.MaxImageSize = int64(c.MaxImageSize) << 20
+	cfg.ProfileMemory = c.ProfileMem
+	if c.GetSource != nil {
+		cfg.GetSource = c.GetSource
+	}
+	// ignore unknowns we don't care about, since we are not removing unknowns with packages
+	cfg.Filter = filter
+	cfg.MaxErrorsPerFile = c.MaxErrorsPerFile
+	cfg.Output = c.Output
+	return cfg, nil
+}
+
+// registryOptions returns the Syft registry options with the configured credentials, nil uses the defaults
+func (c Config) registryOptions() *image.RegistryOptions {
+	if len(c.Registries) == 0 {
+		return nil
+	}
+	opts := &image.RegistryOptions{}
+	for _, r := range c.Registries {
+		opts.Credentials = append(opts.Credentials, image.RegistryCredentials{
+			Authority: r.Authority,
+			Username:  r.Username,
+			Password:  r.Password,
+			Token:     r.Token,
+		})
+	}
+	return opts
+}
+
+// hashAlgorithms are the supported digest algorithms by name
+var hashAlgorithms = map[string]crypto.Hash{
+	"md5":    crypto.MD5,
+	"sha1":   crypto.SHA1,
+	"sha224": crypto.SHA224,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+// hashers returns the configured Syft file hashers, which are always empty when hashing is disabled
+func (c Config) hashers() ([]crypto.Hash, error) {
+	if c.NoHash {
+		return nil, nil
+	}
+	var out []crypto.Hash
+	for _, name := range c.Hashers {
+		h, ok := hashAlgorithms[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported hash algorithm %q", name)
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// stringList is a flag.Value for a comma-separated list, which replaces any existing values when set
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = nil
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+func (c Config) validate() error {
+	if c.StartAt < 0 {
+		return fmt.Errorf("start must be >= 0, got: %v", c.StartAt)
+	}
+	if c.Count < 0 {
+		return fmt.Errorf("count must be >= 0, got: %v", c.Count)
+	}
+	if c.MaxImageSize < 0 {
+		return fmt.Errorf("max-image-size must be >= 0, got: %v", c.MaxImageSize)
+	}
+	if (len(c.Include) > 0 || len(c.Exclude) > 0) && c.SourceType != "image" {
+		return fmt.Errorf("include and exclude are only supported for the image source type")
+	}
+	if _, err := newImageNameFilter(c.Include, c.Exclude); err != nil {
+		return err
+	}
+	for _, globs := range [][]string{c.PathInclude, c.PathExclude} {
+		if _, err := newPathFilter(globs, false); err != nil {
+			return err
+		}
+	}
+	if c.ResolveDigests && c.SourceType != "image" {
+		return fmt.Errorf("resolve-digests is only supported for the image source type")
+	}
+	if c.Deadline < 0 {
+		return fmt.Errorf("deadline must be >= 0, got: %v", c.Deadline)
+	}
+	if c.Sample < 0 {
+		return fmt.Errorf("sample must be >= 0, got: %v", c.Sample)
+	}
+	if c.Parallelism < 1 {
+		return fmt.Errorf("parallelism must be >= 1, got: %v", c.Parallelism)
+	}
+	if c.ImageParallelism < 1 {
+		return fmt.Errorf("image-parallelism must be >= 1, got: %v", c.ImageParallelism)
+	}
+	if c.TopDirs < 0 {
+		return fmt.Errorf("top-dirs must be >= 0, got: %v", c.TopDirs)
+	}
+	if c.TopExtensions < 0 {
+		return fmt.Errorf("top-extensions must be >= 0, got: %v", c.TopExtensions)
+	}
+	if c.TopLayers < 0 {
+		return fmt.Errorf("top-layers must be >= 0, got: %v", c.TopLayers)
+	}
+	if c.RatioThreshold < 0 {
+		return fmt.Errorf("ratio-threshold must be >= 0, got: %v", c.RatioThreshold)
+	}
+	if c.DirDepth < 0 {
+		return fmt.Errorf("dir-depth must be >= 0, got: %v", c.DirDepth)
+	}
+	if c.MaxMemory < 0 {
+		return fmt.Errorf("max-memory must be >= 0, got: %v", c.MaxMemory)
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("max-retries must be >= 0, got: %v", c.MaxRetries)
+	}
+	if c.MaxFailures < 0 {
+		return fmt.Errorf("max-failures must be >= 0, got: %v", c.MaxFailures)
+	}
+	if c.MaxErrorsPerFile < 0 {
+		return fmt.Errorf("max-errors-per-file must be >= 0, got: %v", c.MaxErrorsPerFile)
+	}
+	if c.ImageTimeout <= 0 {
+		return fmt.Errorf("image-timeout must be > 0, got: %v", c.ImageTimeout)
+	}
+	if _, err := c.hashers(); err != nil {
+		return err
+	}
+	if len(c.Providers) == 0 {
+		return fmt.Errorf("at least one provider is required")
+	}
+	if c.ResultDir == "" {
+		return fmt.Errorf("result directory must not be empty")
+	}
+	if c.Keep < 0 {
+		return fmt.Errorf("keep must be >= 0, got: %v", c.Keep)
+	}
+	if c.Keep > 0 && !c.DateDirs {
+		return fmt.Errorf("keep requires date-dirs")
+	}
+	if c.DateDirs && c.Resume {
+		return fmt.Errorf("resume is not supported with date-dirs, since every run writes to a new directory")
+	}
+	if c.SkipExisting && c.DateDirs {
+		return fmt.Errorf("skip-existing is not supported with date-dirs, since every run writes to a new directory")
+	}
+	if c.SkipExisting && c.SingleFile != "" {
+		return fmt.Errorf("skip-existing requires a result file per image, it is not supported with single-file")
+	}
+	if c.SkipExisting && c.S3DeleteLocal {
+		return fmt.Errorf("skip-existing is not supported with s3-delete-local, since uploaded result files are removed")
+	}
+	if _, err := newResultNamer(c.ResultDir, c.resultExt(), c.FilenameTemplate, "", time.Now()); err != nil {
+		return err
+	}
+	if !slices.Contains(resultFormats, c.Format) {
+		return fmt.Errorf("unsupported format %q, must be one of: %s", c.Format, strings.Join(resultFormats, ", "))
+	}
+	if c.Separator != "" {
+		if c.Format == "json" {
+			return fmt.Errorf("separator is not supported with the json format")
+		}
+		if _, err := parseSeparator(c.Separator); err != nil {
+			return err
+		}
+	}
+	if !slices.Contains(logFormats, c.LogFormat) {
+		return fmt.Errorf("unsupported log format %q, must be one of: %s", c.LogFormat, strings.Join(logFormats, ", "))
+	}
+	if c.EmitSBOM != "" && !slices.Contains(sbomFormats, c.EmitSBOM) {
+		return fmt.Errorf("unsupported SBOM format %q, must be one of: %s", c.EmitSBOM, strings.Join(sbomFormats, ", "))
+	}
+	if !slices.Contains(sourceTypes, c.SourceType) {
+		return fmt.Errorf("unsupported source type %q, must be one of: %s", c.SourceType, strings.Join(sourceTypes, ", "))
+	}
+	if c.SourceType != "image" && c.listsImages() {
+		return fmt.Errorf("source type %q requires paths as arguments, from images-file or images-stdin", c.SourceType)
+	}
+	if len(c.Platforms) > 0 && c.SourceType != "image" {
+		return fmt.Errorf("platform is only supported for the image source type")
+	}
+	for _, p := range c.Platforms {
+		if _, err := image.NewPlatform(p); err != nil {
+			return err
+		}
+	}
+	if source.ParseScope(c.Scope) == source.UnknownScope {
+		return fmt.Errorf("unsupported scope %q, must be one of: %v", c.Scope, source.AllScopes)
+	}
+	if !slices.Contains(sourceRegistries, c.SourceRegistry) {
+		return fmt.Errorf("unsupported source registry %q, must be one of: %s", c.SourceRegistry, strings.Join(sourceRegistries, ", "))
+	}
+	if c.Tags < 0 {
+		return fmt.Errorf("tags must be >= 0, got: %v", c.Tags)
+	}
+	if c.PageSize < 1 || c.PageSize > dockerHubMaxPageSize {
+		return fmt.Errorf("page-size must be between 1 and %v, got: %v", dockerHubMaxPageSize, c.PageSize)
+	}
+	if c.StartPage < 1 {
+		return fmt.Errorf("start-page must be >= 1, got: %v", c.StartPage)
+	}
+	if c.StartPage > 1 && c.StartURL != "" {
+		return fmt.Errorf("only one of start-page and start-url may be specified")
+	}
+	if c.StartPage > 1 || c.StartURL != "" {
+		if c.SourceRegistry != "dockerhub" || !c.listsImages() {
+			return fmt.Errorf("start-page and start-url only apply to listing images from dockerhub")
+		}
+		if len(c.namespaces()) > 1 {
+			return fmt.Errorf("start-page and start-url require a single namespace")
+		}
+	}
+	if c.StartURL != "" {
+		if u, err := url.Parse(c.StartURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("start-url must be an absolute URL, got: %s", c.StartURL)
+		}
+	}
+	if c.HTTPTimeout <= 0 {
+		return fmt.Errorf("http-timeout must be > 0, got: %v", c.HTTPTimeout)
+	}
+	for _, namespace := range c.namespaces() {
+		if namespace == "" {
+			return fmt.Errorf("namespace must not be empty")
+		}
+	}
+	for _, r := range c.Registries {
+		if r.Token == "" && (r.Username == "" || r.Password == "") {
+			return fmt.Errorf("registry credentials require a username and password, or a token: %s", r)
+		}
+	}
+	if c.RegressionThreshold < -1 {
+		return fmt.Errorf("regression-threshold must be >= -1, got: %v", c.RegressionThreshold)
+	}
+	if c.Baseline != "" {
+		if _, err := os.Stat(c.Baseline); err != nil {
+			return fmt.Errorf("baseline must be an existing result directory or file: %s", c.Baseline)
+		}
+	}
+	if c.Since != "" {
+		if _, err := os.Stat(c.Since); err != nil {
+			return fmt.Errorf("since must be an existing result directory or file: %s", c.Since)
+		}
+	}
+	if c.Stream && (c.Dedup || c.Baseline != "" || c.Since != "") {
+		return fmt.Errorf("stream does not support dedup, baseline or since, which need all unknowns of an image")
+	}
+	if c.Stream && c.SingleFile != "" {
+		return fmt.Errorf("stream does not support single-file, since rows of images scanned concurrently would interleave")
+	}
+	if (c.S3Prefix != "" || c.S3DeleteLocal) && c.S3Bucket == "" {
+		return fmt.Errorf("s3-prefix and s3-delete-local require s3-bucket")
+	}
+	if c.SingleFile != "" && c.Resume && c.Format == "json" {
+		return fmt.Errorf("single-file json results can't be resumed, use csv or tsv")
+	}
+	if c.Append && c.SingleFile == "" {
+		return fmt.Errorf("append requires single-file")
+	}
+	if c.Append && c.Format == "json" {
+		return fmt.Errorf("json results can't be appended to, use csv or tsv")
+	}
+	if c.Dedup && c.NoHash {
+		return fmt.Errorf("dedup requires file hashing, since files are identified by sha256")
+	}
+	if c.ImagesFile != "" && c.ImagesStdin {
+		return fmt.Errorf("only one of images-file and images-stdin may be specified")
+	}
+	if len(c.Images) > 0 && (c.ImagesFile != "" || c.ImagesStdin) {
+		return fmt.Errorf("images can't be given as arguments with images-file or images-stdin")
+	}
+	if c.ImagesFile != "" {
+		if _, err := os.Stat(c.ImagesFile); err != nil {
+			return fmt.Errorf("unable to read images file: %w", err)
+		}
+	}
+	if len(c.Catalogers) > 0 || len(c.ExcludeCatalogers) > 0 {
+		if err := checkCatalogerSelection(c.catalogerSelection()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	_ "github.com/glebarez/sqlite"
+)
+
+// dbMigrations upgrade the database schema in order, the schema version of a database is the number applied;
+// databases created before the schema was versioned are version 0 and may already have some of the changes, so
+// each migration must be safe to apply to them
+var dbMigrations = []func(tx *sql.Tx) error{
+	// 1: the unknowns table
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+CREATE TABLE IF NOT EXISTS unknowns (
+	image TEXT,
+	file TEXT,
+	task TEXT,
+	error TEXT,
+	scanned_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS unknowns_task ON unknowns(task);
+`)
+		return err
+	},
+	// 2: the run each row was scanned in
+	addRunIDColumn,
+	// 3: the platform each row was scanned on, empty for the host platform, and an index to replace the rows of a
+	// scan in a run
+	func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`ALTER TABLE unknowns ADD COLUMN platform TEXT`); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS unknowns_run_image ON unknowns(run_id, image)`)
+		return err
+	},
+}
+
+// resultDB stores unknown rows in a SQLite database, serializing writes from concurrent workers; workers insert
+// their own batches under a lock rather than through a writer goroutine, so a failed insert fails the image it
+// belongs to
+type resultDB struct {
+	db   *sql.DB
+	lock sync.Mutex
+}
+
+func openResultDB(path string) (*resultDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database %q: %w", path, err)
+	}
+	// sqlite only supports a single writer
+	db.SetMaxOpenConns(1)
+
+	if err = migrate(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("unable to update database schema: %w", err)
+	}
+	return &resultDB{db: db}, nil
+}
+
+// migrate applies the migrations the database does not have yet, each in its own transaction with the version it
+// brings the schema to
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS metadata (name TEXT PRIMARY KEY, value TEXT)`)
+	if err != nil {
+		return err
+	}
+	var version int
+	err = db.QueryRow(`SELECT value FROM metadata WHERE name = 'schema_version'`).Scan(&version)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if version > len(dbMigrations) {
+		return fmt.Errorf("schema version %v is newer than this version of the analyzer supports (%v), upgrade it to use this database",
+			version, len(dbMigrations))
+	}
+
+	for ; version < len(dbMigrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err = dbMigrations[version](tx); err == nil {
+			_, err = tx.Exec(`INSERT OR REPLACE INTO metadata (name, value) VALUES ('schema_version', ?)`, version+1)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %v: %w", version+1, err)
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRunIDColumn adds the run_id column, unless the database was created with it before the schema was versioned
+func addRunIDColumn(tx *sql.Tx) error {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('unknowns') WHERE name = 'run_id'`).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = tx.Exec(`ALTER TABLE unknowns ADD COLUMN run_id TEXT`)
+	return err
+}
+
+// insert writes the rows in a single transaction with one prepared statement, since committing each row on its own
+// syncs the database for every row
+func (r *resultDB) insert(rows []unknownRow) error {
+	return r.write(rows, nil)
+}
+
+// replace deletes the rows an image scanned on the platform already has in the run and inserts rows, in the same
+// transaction so a retried image never has both its earlier and new rows
+func (r *resultDB) replace(runID, image, platform string, rows []unknownRow) error {
+	return r.write(rows, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM unknowns WHERE run_id = ? AND image = ? AND IFNULL(platform, '') = ?`, runID, image, platform)
+		return err
+	})
+}
+
+// write inserts the rows in a transaction, after running before in it when it is set
+func (r *resultDB) write(rows []unknownRow, before func(tx *sql.Tx) error) (err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if before != nil {
+		if err = before(tx); err != nil {
+			return err
+		}
+	}
+	stmt, err := tx.Prepare(`INSERT INTO unknowns (image, platform, file, task, error, scanned_at, run_id) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, row := range rows {
+		if _, err = stmt.Exec(row.Image, row.Platform, row.File, row.Task, row.Error, row.ScannedAt, row.RunID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *resultDB) Close() error {
+	return r.db.Close()
+}
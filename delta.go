@@ -0,0 +1,142 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// deltaFilePrefix replaces the result file prefix in the names of delta files, so they are never read back as results
+const deltaFilePrefix = "delta-"
+
+// deltaPair is the unit of change between runs, a file an error was reported for by a task
+type deltaPair struct {
+	task, file string
+}
+
+// sinceRun holds the unknowns of a previous run, each scanned image's change in unknowns since that run is written
+// to a delta file and totaled for the run
+type sinceRun struct {
+	dir  string
+	rows map[string][]unknownRow
+
+	lock              sync.Mutex
+	images, changed   int
+	previous, current int
+	added, removed    int
+}
+
+// loadSinceRun reads the result files of a previous run in dir, or the single result file when dir is a file
+func loadSinceRun(dir string) (*sinceRun, error) {
+	rows, err := readResultDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read previous run: %w", err)
+	}
+	return &sinceRun{dir: dir, rows: rows}, nil
+}
+
+func deltaPairs(rows []unknownRow) map[deltaPair]struct{} {
+	out := map[deltaPair]struct{}{}
+	for _, row := range rows {
+		out[deltaPair{task: row.Task, file: row.File}] = struct{}{}
+	}
+	return out
+}
+
+// sinceChange is the change in the unknowns of a scanned image since the previous run
+type sinceChange struct {
+	previous, current int
+	added, removed    []deltaPair
+}
+
+// compare returns the change in the unknowns of a scanned image since the previous run
+func (d *sinceRun) compare(key string, rows []unknownRow) sinceChange {
+	previous, current := deltaPairs(d.rows[key]), deltaPairs(rows)
+	c := sinceChange{previous: len(previous), current: len(current)}
+	for p := range current {
+		if _, ok := previous[p]; !ok {
+			c.added = append(c.added, p)
+		}
+	}
+	for p := range previous {
+		if _, ok := current[p]; !ok {
+			c.removed = append(c.removed, p)
+		}
+	}
+	return c
+}
+
+// write writes the added and removed pairs to the image's delta file at path when there are any, otherwise removing
+// the delta file of an earlier scan
+func (c sinceChange) write(path string) error {
+	if len(c.added)+len(c.removed) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700|os.ModeDir); err != nil {
+		return err
+	}
+	return writeDeltaFile(path, c.added, c.removed)
+}
+
+// record totals the change of an image once it is finished, so an image which fails isn't counted again when it is
+// retried
+func (d *sinceRun) record(c sinceChange) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.images++
+	d.previous += c.previous
+	d.current += c.current
+	d.added += len(c.added)
+	d.removed += len(c.removed)
+	if len(c.added)+len(c.removed) > 0 {
+		d.changed++
+	}
+}
+
+func sortPairs(pairs []deltaPair) {
+	slices.SortFunc(pairs, func(a, b deltaPair) int {
+		return cmp.Or(strings.Compare(a.file, b.file), strings.Compare(a.task, b.task))
+	})
+}
+
+func writeDeltaFile(path string, added, removed []deltaPair) error {
+	sortPairs(added)
+	sortPairs(removed)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"CHANGE", "TASK", "FILE"})
+	for _, p := range added {
+		_ = w.Write([]string{"added", p.task, p.file})
+	}
+	for _, p := range removed {
+		_ = w.Write([]string{"removed", p.task, p.file})
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// print prints the total change in unknowns of the scanned images, images which were not scanned are not included
+func (d *sinceRun) print(w io.Writer) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	fmt.Fprintf(w, "unknowns since %s: %v images changed of %v; %v before; %v now; net change: %+d (%v added, %v removed)\n",
+		d.dir, d.changed, d.images, d.previous, d.current, d.current-d.previous, d.added, d.removed)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// diffConfig holds the parameters of the diff command
+type diffConfig struct {
+	// Baseline is the result directory, or single result file, of the earlier run
+	Baseline string
+	// Current is the result directory, or single result file, of the later run
+	Current string
+	// OutputDir is an optional directory new.csv and fixed.csv are written to
+	OutputDir string
+	// Fingerprint matches unknowns by normalized error rather than by file
+	Fingerprint bool
+	// RegressionThreshold fails the diff when there are more new unknowns, -1 disables the check
+	RegressionThreshold int
+	// Output receives the counts of new and fixed unknowns, by default stdout
+	Output io.Writer
+}
+
+func parseDiffConfig(args []string) (diffConfig, error) {
+	cfg := diffConfig{RegressionThreshold: -1, Output: os.Stdout}
+
+	fs := flag.NewFlagSet("analyzer diff", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: analyzer diff [flags] <baseline> <current>\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.OutputDir, "o", cfg.OutputDir, "directory to write new.csv and fixed.csv to")
+	fs.BoolVar(&cfg.Fingerprint, "fingerprint", cfg.Fingerprint, "match unknowns by normalized error instead of by file")
+	fs.IntVar(&cfg.RegressionThreshold, "regression-threshold", cfg.RegressionThreshold, "fail when there are more new unknowns than this, -1 to disable")
+
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return cfg, err
+	}
+	if len(args) != 2 {
+		return cfg, fmt.Errorf("a baseline and a current result directory are required")
+	}
+	cfg.Baseline, cfg.Current = args[0], args[1]
+
+	for _, path := range args {
+		if _, err := os.Stat(path); err != nil {
+			return cfg, fmt.Errorf("not a result directory or file: %s", path)
+		}
+	}
+	if cfg.RegressionThreshold < -1 {
+		return cfg, fmt.Errorf("regression-threshold must be >= -1, got: %v", cfg.RegressionThreshold)
+	}
+	return cfg, nil
+}
+
+// diff compares the unknowns of two runs without scanning, only images in the current run are compared
+func diff(cfg diffConfig) error {
+	base, err := loadBaseline(cfg.Baseline, cfg.Fingerprint)
+	if err != nil {
+		return err
+	}
+	current, err := loadBaseline(cfg.Current, false)
+	if err != nil {
+		return err
+	}
+
+	var added []unknownRow
+	for key, rows := range sorted(current.rows) {
+		newRows := base.newRows(key, rows)
+		base.record(key, rows, len(newRows))
+		added = append(added, newRows...)
+	}
+	fixed := base.fixedRows()
+
+	if cfg.OutputDir != "" {
+		if err = os.MkdirAll(cfg.OutputDir, 0700|os.ModeDir); err != nil {
+			return err
+		}
+		for name, rows := range map[string][]unknownRow{"new.csv": added, "fixed.csv": fixed} {
+			if err = writeResultFile(filepath.Join(cfg.OutputDir, name), "csv", rows, optionalColumns{}, ','); err != nil {
+				return fmt.Errorf("unable to write %s: %w", name, err)
+			}
+		}
+	}
+
+	fmt.Fprintf(cfg.Output, "unknowns since baseline: %v new; %v fixed\n", len(added), len(fixed))
+	if cfg.RegressionThreshold >= 0 && len(added) > cfg.RegressionThreshold {
+		return fmt.Errorf("%v new unknowns exceed the regression threshold of %v", len(added), cfg.RegressionThreshold)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	baseline, current := t.TempDir(), t.TempDir()
+	fixedRow := unknownRow{Image: "example/app:1", File: "/old.jar", Task: "java-archive-cataloger", Error: "not a valid zip file"}
+	keptRow := unknownRow{Image: "example/app:1", File: "/app.jar", Task: "java-archive-cataloger", Error: "not a valid zip file"}
+	newRow := unknownRow{Image: "example/app:1", File: "/new.jar", Task: "java-archive-cataloger", Error: "not a valid zip file"}
+	for dir, rows := range map[string][]unknownRow{baseline: {fixedRow, keptRow}, current: {keptRow, newRow}} {
+		if err := writeResultFile(resultFilePath(dir, "example/app:1", "csv"), "csv", rows, optionalColumns{}, ','); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	err := diff(diffConfig{Baseline: baseline, Current: current, RegressionThreshold: 0, Output: &out})
+	if err == nil || !strings.Contains(err.Error(), "1 new unknowns exceed the regression threshold of 0") {
+		t.Errorf("diff() = %v, want the new unknown to exceed the threshold", err)
+	}
+	if got, want := out.String(), "unknowns since baseline: 1 new; 1 fixed\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
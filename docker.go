@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// dockerImage returns the ID of the image the Docker daemon has for ref, and the number of tags referencing it
+func dockerImage(ref string) (id string, tags int, err error) {
+	out, err := docker("image", "inspect", "--format", "{{.Id}} {{len .RepoTags}}", ref)
+	if err != nil {
+		return "", 0, err
+	}
+	id, count, _ := strings.Cut(out, " ")
+	tags, err = strconv.Atoi(count)
+	if err != nil {
+		return "", 0, fmt.Errorf("unexpected docker image inspect output: %q", out)
+	}
+	return id, tags, nil
+}
+
+// dockerImageExists returns true when the Docker daemon has an image for ref, false also when Docker is unavailable
+func dockerImageExists(ref string) bool {
+	_, _, err := dockerImage(ref)
+	return err == nil
+}
+
+// removeDockerImage removes the image pulled as ref from the Docker daemon, matched by the ID the daemon has for ref
+// after the pull; when other tags reference the image only ref is untagged, since the scan didn't pull those
+func removeDockerImage(ref string) error {
+	id, tags, err := dockerImage(ref)
+	if err != nil {
+		return fmt.Errorf("unable to find docker image %s: %w", ref, err)
+	}
+	target := id
+	if tags > 1 {
+		target = ref
+	}
+	if _, err = docker("rmi", target); err != nil {
+		return fmt.Errorf("unable to remove docker image %s: %w", ref, err)
+	}
+	return nil
+}
+
+// danglingDockerImages returns the IDs of the images in the Docker daemon which no tag references
+func danglingDockerImages() (map[string]struct{}, error) {
+	out, err := docker("images", "--filter", "dangling=true", "--quiet", "--no-trunc")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list dangling docker images: %w", err)
+	}
+	ids := map[string]struct{}{}
+	for _, id := range strings.Fields(out) {
+		ids[id] = struct{}{}
+	}
+	return ids, nil
+}
+
+// removeDanglingDockerImages removes the dangling images left by pulls during the run, those which were already
+// dangling when it started are left alone; it returns the number of images removed
+func removeDanglingDockerImages(before map[string]struct{}) (int, error) {
+	after, err := danglingDockerImages()
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for id := range sorted(after) {
+		if _, ok := before[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if _, err = docker(append([]string{"rmi"}, ids...)...); err != nil {
+		return 0, fmt.Errorf("unable to remove dangling docker images: %w", err)
+	}
+	return len(ids), nil
+}
+
+// refLocks serializes the scans of a ref, so checking whether the Docker daemon has the image, pulling, scanning and
+// removing it isn't interleaved with the scan of another platform of the ref, which would use or remove the same tag
+type refLocks struct {
+	lock  sync.Mutex
+	locks map[string]*refLock
+}
+
+type refLock struct {
+	sync.Mutex
+	users int
+}
+
+// Lock waits for the other scans of ref to finish, returning the unlock function
+func (l *refLocks) Lock(ref string) func() {
+	l.lock.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*refLock{}
+	}
+	r := l.locks[ref]
+	if r == nil {
+		r = &refLock{}
+		l.locks[ref] = r
+	}
+	r.users++
+	l.lock.Unlock()
+
+	r.Lock()
+	return func() {
+		r.Unlock()
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		if r.users--; r.users == 0 {
+			delete(l.locks, ref)
+		}
+	}
+}
+
+// docker runs a docker command, returning the trimmed output and including it in any error
+func docker(args ...string) (string, error) {
+	out, err := run(append([]string{"docker"}, args...)...)
+	out = strings.TrimSpace(out)
+	if err != nil && out != "" {
+		return out, fmt.Errorf("%w: %s", err, out)
+	}
+	return out, err
+}
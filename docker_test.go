@@ -0,0 +1,36 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRefLocks(t *testing.T) {
+	var locks refLocks
+	var inUse, overlapped atomic.Int32
+	wg := sync.WaitGroup{}
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer locks.Lock("alpine:latest")()
+			if inUse.Add(1) > 1 {
+				overlapped.Store(1)
+			}
+			inUse.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if overlapped.Load() != 0 {
+		t.Error("scans of the same ref overlapped")
+	}
+
+	// other refs aren't blocked
+	unlock := locks.Lock("alpine:latest")
+	locks.Lock("busybox:latest")()
+	unlock()
+	if len(locks.locks) != 0 {
+		t.Errorf("locks = %v, want the unused locks removed", locks.locks)
+	}
+}
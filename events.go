@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// event types reported while scanning
+const (
+	eventStarted   = "started"
+	eventCompleted = "completed"
+	eventFailed    = "failed"
+	eventSkipped   = "skipped"
+	eventMissing   = "missing"
+)
+
+// Event reports the progress of a single image scan, fields which don't apply to the event type are empty; Err is
+// the reason a failed image failed, or an image which was not completed was skipped
+type Event struct {
+	Type         string
+	Ref          string
+	Index        int
+	Duration     time.Duration
+	FileCount    int
+	UnknownCount int
+	Err          error
+}
+
+// EventHandler receives scan events, it is called concurrently from all workers
+type EventHandler func(Event)
+
+// newTextEventHandler writes events to w as human-readable text
+func newTextEventHandler(w io.Writer) EventHandler {
+	return func(e Event) {
+		printEvent(w, e)
+	}
+}
+
+func printEvent(w io.Writer, e Event) {
+	switch e.Type {
+	case eventStarted:
+		fmt.Fprintf(w, "Scanning: %v %s\n", e.Index, e.Ref)
+	case eventCompleted:
+		fmt.Fprintf(w, "completed %v '%v' in %v\n", e.Index, e.Ref, e.Duration)
+	case eventFailed:
+		fmt.Fprintf(w, "ERROR: %v %s: %v\n", e.Index, e.Ref, e.Err)
+	case eventMissing:
+		// the registry error is only included in JSON events, a missing tag is common when listing repositories
+		fmt.Fprintf(w, "Missing: %v %s\n", e.Index, e.Ref)
+	case eventSkipped:
+		if e.Err != nil {
+			fmt.Fprintf(w, "Skipping: %v %s: %v\n", e.Index, e.Ref, e.Err)
+			return
+		}
+		fmt.Fprintf(w, "Skipping completed: %v %s\n", e.Index, e.Ref)
+	}
+}
+
+// newJSONEventHandler writes events to w as newline-delimited JSON
+func newJSONEventHandler(w io.Writer) EventHandler {
+	type eventJSON struct {
+		Type         string  `json:"type"`
+		Ref          string  `json:"ref"`
+		Index        int     `json:"index"`
+		Duration     float64 `json:"duration,omitempty"`
+		FileCount    int     `json:"fileCount,omitempty"`
+		UnknownCount int     `json:"unknownCount,omitempty"`
+		Error        string  `json:"error,omitempty"`
+	}
+
+	lock := sync.Mutex{}
+	enc := json.NewEncoder(w)
+	return func(e Event) {
+		out := eventJSON{
+			Type:         e.Type,
+			Ref:          e.Ref,
+			Index:        e.Index,
+			Duration:     e.Duration.Seconds(),
+			FileCount:    e.FileCount,
+			UnknownCount: e.UnknownCount,
+		}
+		if e.Err != nil {
+			out.Error = e.Err.Error()
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		_ = enc.Encode(out)
+	}
+}
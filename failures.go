@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const failuresFile = "failures.csv"
+
+// imageFailure is an image which could not be scanned, with what is needed to scan it again
+type imageFailure struct {
+	idx      int
+	ref      string
+	platform string
+	err      error
+}
+
+// key identifies the failed scan, as in the checkpoint
+func (f imageFailure) key() string {
+	return scanKey(f.ref, f.platform)
+}
+
+// failureLog records images which could not be scanned to a CSV file in the result directory
+type failureLog struct {
+	lock     sync.Mutex
+	f        *os.File
+	w        *csv.Writer
+	failures []imageFailure
+}
+
+// openFailureLog replaces the failures file in the result directory, returning the failures an earlier run recorded
+// in it so they can be retried
+func openFailureLog(resultDir string) (*failureLog, []imageFailure, error) {
+	path := filepath.Join(resultDir, failuresFile)
+	previous, err := readFailures(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	l := &failureLog{f: f, w: csv.NewWriter(f)}
+	if err = l.writeHeader(); err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return l, previous, nil
+}
+
+// readFailures reads a failures file, which doesn't record the index of each image so it is -1; files from before
+// the PLATFORM column was added have the scan key in the IMAGE column
+func readFailures(path string) ([]imageFailure, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read failures: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read failures: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var failures []imageFailure
+	for _, record := range records[1:] {
+		failure := imageFailure{idx: -1, ref: field(record, "IMAGE"), platform: field(record, "PLATFORM"), err: errors.New(field(record, "ERROR"))}
+		if failure.ref != "" {
+			failures = append(failures, failure)
+		}
+	}
+	return failures, nil
+}
+
+func (l *failureLog) writeHeader() error {
+	if err := l.w.Write([]string{"IMAGE", "PLATFORM", "ERROR"}); err != nil {
+		return err
+	}
+	l.w.Flush()
+	return l.w.Error()
+}
+
+// record writes a failure immediately, so it is not lost if the run is interrupted
+func (l *failureLog) record(failure imageFailure) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.failures = append(l.failures, failure)
+	if err := l.w.Write([]string{failure.ref, failure.platform, failure.err.Error()}); err != nil {
+		return err
+	}
+	l.w.Flush()
+	return l.w.Error()
+}
+
+// resolve updates a failure after it was retried, removing it when err is nil or replacing its error, and rewrites
+// the failures file
+func (l *failureLog) resolve(failure imageFailure, err error) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.failures = slices.DeleteFunc(l.failures, func(f imageFailure) bool {
+		return f.key() == failure.key()
+	})
+	if err != nil {
+		failure.err = err
+		l.failures = append(l.failures, failure)
+	}
+
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := l.writeHeader(); err != nil {
+		return err
+	}
+	for _, f := range l.failures {
+		if err := l.w.Write([]string{f.ref, f.platform, f.err.Error()}); err != nil {
+			return err
+		}
+	}
+	l.w.Flush()
+	return l.w.Error()
+}
+
+// list returns all recorded failures ordered by image
+func (l *failureLog) list() []imageFailure {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	out := slices.Clone(l.failures)
+	slices.SortFunc(out, func(a, b imageFailure) int {
+		return strings.Compare(a.key(), b.key())
+	})
+	return out
+}
+
+func (l *failureLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.w.Flush()
+	return l.f.Close()
+}
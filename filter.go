@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/kzantow-anchore/syft-unknown-analyzer/analyzer"
+
+	"github.com/anchore/syft/syft/file"
+)
+
+// defaultErrorFilters are used when no filter file is provided
+var defaultErrorFilters = []string{
+	"unable to determine ELF features",
+}
+
+// errorFilter drops errors containing a substring or matching a regular expression; errors are matched as Syft
+// reported them, prefixed with the task
+type errorFilter struct {
+	pattern string
+	regex   *regexp.Regexp
+}
+
+func newErrorFilter(pattern string) (*errorFilter, error) {
+	f := &errorFilter{pattern: pattern}
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+		}
+		f.regex = regex
+	}
+	return f, nil
+}
+
+func (f *errorFilter) Keep(_ file.Coordinates, task, err string) bool {
+	if task != "" {
+		err = task + ": " + err
+	}
+	if f.regex != nil {
+		return !f.regex.MatchString(err)
+	}
+	return !strings.Contains(err, f.pattern)
+}
+
+// taskFilter drops errors reported by a task, such as a cataloger
+type taskFilter string
+
+func (f taskFilter) Keep(_ file.Coordinates, task, _ string) bool {
+	return task != string(f)
+}
+
+// extensionFilter keeps only files with one of the extensions, compared case-insensitively
+type extensionFilter []string
+
+func newExtensionFilter(extensions []string) extensionFilter {
+	var out extensionFilter
+	for _, ext := range extensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		out = append(out, strings.ToLower(ext))
+	}
+	return out
+}
+
+func (f extensionFilter) Keep(coord file.Coordinates, _, _ string) bool {
+	return slices.Contains(f, strings.ToLower(path.Ext(coord.RealPath)))
+}
+
+// pathFilter keeps files whose real path matches one of the globs, or with exclude those matching none; globs are
+// matched as Syft's FilesByGlob matches them, relative to the root
+type pathFilter struct {
+	globs   []string
+	exclude bool
+}
+
+func newPathFilter(globs []string, exclude bool) (*pathFilter, error) {
+	f := &pathFilter{exclude: exclude}
+	for _, glob := range globs {
+		if !strings.HasPrefix(glob, "/") {
+			glob = "/" + glob
+		}
+		if !doublestar.ValidatePattern(glob) {
+			return nil, fmt.Errorf("invalid path glob: %q", glob)
+		}
+		f.globs = append(f.globs, glob)
+	}
+	return f, nil
+}
+
+func (f *pathFilter) Keep(coord file.Coordinates, _, _ string) bool {
+	for _, glob := range f.globs {
+		// the globs are validated when the filter is created
+		if ok, _ := doublestar.Match(glob, coord.RealPath); ok {
+			return !f.exclude
+		}
+	}
+	return f.exclude
+}
+
+// countedFilter counts the unknowns a filter removed, named by what it removes
+type countedFilter struct {
+	name    string
+	filter  analyzer.Filter
+	removed atomic.Int64
+}
+
+// filterChain reports an unknown only when every filter keeps it, an unknown is counted as removed by the first
+// filter which drops it
+type filterChain []*countedFilter
+
+func (c filterChain) Keep(coord file.Coordinates, task, err string) bool {
+	for _, f := range c {
+		if !f.filter.Keep(coord, task, err) {
+			f.removed.Add(1)
+			return false
+		}
+	}
+	return true
+}
+
+func (c *filterChain) add(name string, filter analyzer.Filter) {
+	*c = append(*c, &countedFilter{name: name, filter: filter})
+}
+
+// newFilterChain creates the error filters from patterns, patterns prefixed with "re:" are regular expressions and
+// all others are substrings, followed by the filters of the config file
+func newFilterChain(patterns []string, filters []FilterConfig) (filterChain, error) {
+	var chain filterChain
+	for _, pattern := range patterns {
+		f, err := newErrorFilter(pattern)
+		if err != nil {
+			return nil, err
+		}
+		chain.add("errors matching: "+pattern, f)
+	}
+	for i, fc := range filters {
+		if fc.set() != 1 {
+			return nil, fmt.Errorf("filter %v must set exactly one of: dropError, dropTask, keepExtensions", i+1)
+		}
+		switch {
+		case fc.DropError != "":
+			f, err := newErrorFilter(fc.DropError)
+			if err != nil {
+				return nil, err
+			}
+			chain.add("errors matching: "+fc.DropError, f)
+		case fc.DropTask != "":
+			chain.add("errors from task: "+fc.DropTask, taskFilter(fc.DropTask))
+		default:
+			f := newExtensionFilter(fc.KeepExtensions)
+			chain.add("files without extension: "+strings.Join(f, ", "), f)
+		}
+	}
+	return chain, nil
+}
+
+// loadFilters creates the filter chain from the patterns of the filter file, or the default filters if no file is
+// provided, the filters of the config file, and the path globs
+func loadFilters(cfg Config) (filterChain, error) {
+	patterns := defaultErrorFilters
+	if cfg.FilterFile != "" {
+		var err error
+		if patterns, err = readFilterFile(cfg.FilterFile); err != nil {
+			return nil, err
+		}
+	}
+	chain, err := newFilterChain(patterns, cfg.Filters)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.PathInclude) > 0 {
+		f, err := newPathFilter(cfg.PathInclude, false)
+		if err != nil {
+			return nil, err
+		}
+		chain.add("files not matching: "+strings.Join(f.globs, ", "), f)
+	}
+	if len(cfg.PathExclude) > 0 {
+		f, err := newPathFilter(cfg.PathExclude, true)
+		if err != nil {
+			return nil, err
+		}
+		chain.add("files matching: "+strings.Join(f.globs, ", "), f)
+	}
+	return chain, nil
+}
+
+// readFilterFile reads one pattern per line from a file, skipping blank lines and # comments
+func readFilterFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open filter file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read filter file: %w", err)
+	}
+	return patterns, nil
+}
+
+// printTally prints the number of unknowns each filter removed
+func (c filterChain) printTally(w io.Writer) {
+	for _, f := range c {
+		fmt.Fprintf(w, "filtered %v %s\n", f.removed.Load(), f.name)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "regexp"
+
+// fingerprintReplacements canonicalize volatile fragments of error messages, in order, so errors which differ only
+// by the file, offset or address they refer to share a fingerprint
+var fingerprintReplacements = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s'"()]+`), "<url>"},
+	{regexp.MustCompile(`/[^\s:'"(),]+`), "<path>"},
+	{regexp.MustCompile(`\b0[xX][0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{12,}\b`), "<hex>"},
+	{regexp.MustCompile(`\b\d+(?:\.\d+)*\b`), "<n>"},
+}
+
+// fingerprint returns the error message with URLs, paths, hex values and numbers replaced by placeholders
+func fingerprint(msg string) string {
+	for _, r := range fingerprintReplacements {
+		msg = r.pattern.ReplaceAllString(msg, r.replacement)
+	}
+	return msg
+}
@@ -0,0 +1,28 @@
+package main
+
+import "strings"
+
+// errorHints map fragments of known errors to guidance on what to do about them, the first matching hint is used
+var errorHints = []struct {
+	match, hint string
+}{
+	{"unable to determine ELF features", "ELF: expected for stripped binaries, usually ignorable"},
+	{"no package identified in executable file", "executable without package metadata: a binary classifier may be needed to identify it"},
+	{"archive not cataloged", "unexpanded archive: add the format to the archive catalogers"},
+	{"nested archives not cataloged", "nested archives: enable nested archive search for the java catalogers"},
+	{"no package identified in archive", "archive without package metadata, such as a jar without a manifest or pom.properties"},
+	{"not a valid zip file", "file with an archive extension is not a zip archive, usually ignorable"},
+	{"permission error reading file contents", "permission denied: scan with access to all files in the image"},
+	{"unable to read golang version info", "Go binary without build info, such as one built without modules or stripped"},
+	{"requirements.txt", "unparsable or unpinned requirement: pin versions in requirements.txt"},
+}
+
+// hint returns the guidance for an error, empty when the error is not known
+func hint(msg string) string {
+	for _, h := range errorHints {
+		if strings.Contains(msg, h.match) {
+			return h.hint
+		}
+	}
+	return ""
+}
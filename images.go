@@ -0,0 +1,724 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImageLister enumerates image references from a registry, one page at a time
+type ImageLister interface {
+	// Next returns the next page of image references and a token which is empty when there are no more pages
+	Next(ctx context.Context) (images []string, nextToken string, err error)
+}
+
+var sourceRegistries = []string{"dockerhub", "quay", "ghcr"}
+
+// dockerHubMaxPageSize is the largest page size the Docker Hub API accepts
+const dockerHubMaxPageSize = 100
+
+// dockerHubOptions select the pages and images listed from Docker Hub
+type dockerHubOptions struct {
+	// pageSize is the number of repositories in each page
+	pageSize int
+	// startPage is the first page, from 1
+	startPage int
+	// startURL is the first page's URL, replacing pageSize and startPage when set
+	startURL string
+	// maxSize skips tags whose compressed size Docker Hub reports is over this many bytes, 0 for no limit
+	maxSize int64
+}
+
+// firstURL returns the URL of the first page of repositories listed from a namespace
+func (o dockerHubOptions) firstURL(namespace string) string {
+	if o.startURL != "" {
+		return o.startURL
+	}
+	u := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/?page_size=%v", namespace, o.pageSize)
+	if o.startPage > 1 {
+		u += fmt.Sprintf("&page=%v", o.startPage)
+	}
+	return u
+}
+
+// httpDoer sends HTTP requests for the listers, this is satisfied by *http.Client and allows a stub to be provided
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// newHTTPClient returns the client used to list images; unlike http.DefaultClient requests time out, and proxies are
+// configured with the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: http.DefaultTransport,
+	}
+}
+
+// ImageSource yields the references to scan with their index, until the context is done; imagesIterator yields
+// them from the configured images, file, stdin or registry
+type ImageSource func(ctx context.Context, cfg Config) (func(func(int, string) bool), error)
+
+// imagesIterator yields the references to scan, which are filesystem paths for non-image source types
+func imagesIterator(ctx context.Context, cfg Config) (func(func(int, string) bool), error) {
+	images, err := enumerateImages(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DedupInputs {
+		images = dedupIterator(images, maxDedupInputs, cfg.Output)
+	}
+	if len(cfg.Include) > 0 || len(cfg.Exclude) > 0 {
+		filter, err := newImageNameFilter(cfg.Include, cfg.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		images = filter.filterIterator(images)
+	}
+	if cfg.Sample > 0 {
+		return sampleIterator(images, cfg.Sample, cfg.Seed, cfg.StartAt, cfg.Count, cfg.Output), nil
+	}
+	return images, nil
+}
+
+// enumerateImages yields every reference from the images file, stdin or the registry, stopping when the context is
+// done
+func enumerateImages(ctx context.Context, cfg Config) (func(func(int, string) bool), error) {
+	defaultTag := cfg.SourceType == "image"
+	if len(cfg.Images) > 0 {
+		return sliceImagesIterator(cfg.Images, defaultTag), nil
+	}
+	if cfg.ImagesFile != "" {
+		return imagesFileIterator(ctx, cfg.ImagesFile, defaultTag, cfg.Output)
+	}
+	if cfg.ImagesStdin {
+		return readerImagesIterator(ctx, os.Stdin, defaultTag, cfg.Output), nil
+	}
+	client := newHTTPClient(cfg.HTTPTimeout)
+	hub := dockerHubOptions{
+		pageSize:  cfg.PageSize,
+		startPage: cfg.StartPage,
+		startURL:  cfg.StartURL,
+		maxSize:   int64(cfg.MaxImageSize) << 20,
+	}
+	var listers []ImageLister
+	for _, namespace := range cfg.namespaces() {
+		listers = append(listers, newImageLister(client, cfg.Output, cfg.SourceRegistry, namespace, cfg.Tags, hub))
+	}
+	return listerIterator(ctx, cfg.Output, listers...), nil
+}
+
+// maxDedupInputs bounds the references remembered to drop duplicates, so reading an endless stream of references
+// does not grow without limit
+const maxDedupInputs = 1_000_000
+
+// dedupIterator yields each reference once, re-indexing those yielded, and warns of the number of duplicates
+// dropped; once limit references are remembered, duplicates of those not remembered are no longer detected
+func dedupIterator(images func(func(int, string) bool), limit int, out io.Writer) func(func(int, string) bool) {
+	return func(yield func(int, string) bool) {
+		seen := map[string]struct{}{}
+		idx, dropped := 0, 0
+		defer func() {
+			if dropped > 0 {
+				fmt.Fprintf(out, "WARNING: dropped %v duplicate image references from the input\n", dropped)
+			}
+		}()
+		for _, ref := range images {
+			if _, ok := seen[ref]; ok {
+				dropped++
+				continue
+			}
+			if len(seen) < limit {
+				seen[ref] = struct{}{}
+				if len(seen) == limit {
+					fmt.Fprintf(out, "WARNING: remembered %v image references, duplicates of later references are not dropped\n", limit)
+				}
+			}
+			if !yield(idx, ref) {
+				return
+			}
+			idx++
+		}
+	}
+}
+
+// sampleIterator yields a random sample of n of the images from index start, and before start+count when count is
+// > 0, in their original order and with their original indexes; the sample is drawn by reservoir sampling as images
+// are enumerated, so the total doesn't need to be known, and the same seed draws the same sample
+func sampleIterator(images func(func(int, string) bool), n int, seed uint64, start, count int, out io.Writer) func(func(int, string) bool) {
+	type sampled struct {
+		idx int
+		ref string
+	}
+
+	return func(f func(int, string) bool) {
+		rng := rand.New(rand.NewPCG(seed, 0))
+		var reservoir []sampled
+		seen := 0
+		for idx, ref := range images {
+			if idx < start {
+				continue
+			}
+			if count > 0 && idx >= start+count {
+				break
+			}
+			if len(reservoir) < n {
+				reservoir = append(reservoir, sampled{idx: idx, ref: ref})
+			} else if j := rng.IntN(seen + 1); j < n {
+				reservoir[j] = sampled{idx: idx, ref: ref}
+			}
+			seen++
+		}
+
+		slices.SortFunc(reservoir, func(a, b sampled) int { return a.idx - b.idx })
+		fmt.Fprintf(out, "sampled %v of %v images with seed %v\n", len(reservoir), seen, seed)
+		for _, s := range reservoir {
+			if !f(s.idx, s.ref) {
+				return
+			}
+		}
+	}
+}
+
+// refNamespace returns the namespace of an image ref, between the registry and the repository name; Docker Hub
+// images without one, such as nginx:latest, are in library
+func refNamespace(ref string) string {
+	repo := repositoryPath(ref)
+	i := strings.LastIndex(repo, "/")
+	if i < 0 {
+		return "library"
+	}
+	return repo[:i]
+}
+
+// repositoryPath returns the repository of an image ref without the registry, tag or digest, e.g. library/nginx
+func repositoryPath(ref string) string {
+	name, _, _ := strings.Cut(ref, "@")
+	segments := strings.Split(name, "/")
+	if len(segments) > 1 && (strings.ContainsAny(segments[0], ".:") || segments[0] == "localhost") {
+		segments = segments[1:]
+	}
+	last := len(segments) - 1
+	segments[last], _, _ = strings.Cut(segments[last], ":")
+	return strings.Join(segments, "/")
+}
+
+// newImageLister returns a lister for the registry, when tags > 0 the most recent tags of each repository are
+// listed instead of :latest
+func newImageLister(client httpDoer, out io.Writer, registry, namespace string, tags int, hub dockerHubOptions) ImageLister {
+	switch registry {
+	case "quay":
+		return newQuayLister(client, out, namespace, tags)
+	case "ghcr":
+		return newGhcrLister(client, out, namespace, os.Getenv("GITHUB_TOKEN"), tags)
+	default:
+		return newDockerHubLister(client, out, namespace, tags, hub)
+	}
+}
+
+// listerPrefetch is the number of pages of images listed ahead of those being scanned
+const listerPrefetch = 2
+
+// listedPage is a page of images from a lister, or the error listing it
+type listedPage struct {
+	images []string
+	err    error
+}
+
+// listerIterator yields all images from every page of each lister in turn, with indexes continuing across listers;
+// the next pages are listed concurrently, up to listerPrefetch ahead, so scanning doesn't wait on pagination; once
+// the context is done no more pages are requested and no more images are yielded
+func listerIterator(ctx context.Context, out io.Writer, listers ...ImageLister) func(func(int, string) bool) {
+	idx := 0
+
+	return func(f func(int, string) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		for page := range prefetchPages(ctx, listers) {
+			if page.err != nil {
+				fmt.Fprintf(out, "ERROR: unable to list images, stopping enumeration: %v\n", page.err)
+				return
+			}
+			for _, image := range page.images {
+				if ctx.Err() != nil || !f(idx, image) {
+					return
+				}
+				idx++
+			}
+		}
+	}
+}
+
+// prefetchPages lists the pages of each lister in turn into a buffered channel, which is closed after the last page
+// or an error; listing stops when the context is done
+func prefetchPages(ctx context.Context, listers []ImageLister) <-chan listedPage {
+	pages := make(chan listedPage, listerPrefetch)
+	go func() {
+		defer close(pages)
+		for _, lister := range listers {
+			for {
+				images, next, err := lister.Next(ctx)
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case pages <- listedPage{images: images, err: err}:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+				if next == "" {
+					break
+				}
+			}
+		}
+	}()
+	return pages
+}
+
+// dockerHubLister lists the :latest tag, or the most recent tags, of all repositories in a Docker Hub namespace
+type dockerHubLister struct {
+	client    httpDoer
+	out       io.Writer
+	namespace string
+	tags      int
+	maxSize   int64
+	next      string
+}
+
+func newDockerHubLister(client httpDoer, out io.Writer, namespace string, tags int, opts dockerHubOptions) *dockerHubLister {
+	return &dockerHubLister{
+		client:    client,
+		out:       out,
+		namespace: namespace,
+		tags:      tags,
+		maxSize:   opts.maxSize,
+		next:      opts.firstURL(namespace),
+	}
+}
+
+func (l *dockerHubLister) Next(ctx context.Context) ([]string, string, error) {
+	images, next, err := l.page(ctx, l.next)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w; resume listing with --start-url %s", err, l.next)
+	}
+	l.next = next
+	return images, l.next, nil
+}
+
+// page lists the images of the repositories in a page, returning the URL of the next page
+func (l *dockerHubLister) page(ctx context.Context, pageURL string) ([]string, string, error) {
+	names, next, err := getNameList(ctx, l.client, l.out, pageURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var images []string
+	for _, name := range names {
+		// refs are named as docker pull would be given them, which is how earlier results and checkpoints are keyed
+		repo := name
+		if l.namespace != "library" {
+			repo = l.namespace + "/" + name
+		}
+		if l.tags <= 0 {
+			images = append(images, repo+":latest")
+			continue
+		}
+
+		tagsURL := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/%s/tags?page_size=%v&ordering=last_updated", l.namespace, name, l.tags)
+		rsp, err := httpGet(ctx, l.client, l.out, tagsURL, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		var page dockerHubPage
+		err = decodeJSON(rsp, &page)
+		_ = rsp.Body.Close()
+		if err != nil {
+			return nil, "", err
+		}
+		for _, tag := range page.Results {
+			if l.maxSize > 0 && tag.FullSize > l.maxSize {
+				// the compressed size is smaller than the image, so this only skips images which are over the limit
+				fmt.Fprintf(l.out, "Skipping %s:%s, %v MiB compressed\n", repo, tag.Name, tag.FullSize>>20)
+				continue
+			}
+			images = append(images, repo+":"+tag.Name)
+		}
+	}
+	return images, next, nil
+}
+
+// dockerHubPage is a page of results from the Docker Hub repositories and tags APIs
+type dockerHubPage struct {
+	Next    string `json:"next"`
+	Results []struct {
+		Name string `json:"name"`
+		// FullSize is the compressed size of a tag's image, only in tags results
+		FullSize int64 `json:"full_size"`
+	} `json:"results"`
+}
+
+// getNameList returns the sorted names from a Docker Hub results page and the URL of the next page
+func getNameList(ctx context.Context, client httpDoer, out io.Writer, url string) ([]string, string, error) {
+	rsp, err := httpGet(ctx, client, out, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	var page dockerHubPage
+	if err = decodeJSON(rsp, &page); err != nil {
+		return nil, "", err
+	}
+
+	var names []string
+	for _, result := range page.Results {
+		if result.Name == "" {
+			return nil, "", fmt.Errorf("unexpected result without a name from %s", url)
+		}
+		names = append(names, result.Name)
+	}
+	slices.Sort(names)
+	return names, page.Next, nil
+}
+
+// decodeJSON decodes a response body, reporting the request URL when the body is not the expected shape, such as
+// for maintenance pages or error objects
+func decodeJSON(rsp *http.Response, into any) error {
+	if err := json.NewDecoder(rsp.Body).Decode(into); err != nil {
+		return fmt.Errorf("unable to decode response from %s: %w", rsp.Request.URL, err)
+	}
+	return nil
+}
+
+// quayLister lists the :latest tag, or the most recent tags, of all public repositories in a Quay.io namespace
+type quayLister struct {
+	client    httpDoer
+	out       io.Writer
+	namespace string
+	tags      int
+	next      string
+	done      bool
+}
+
+func newQuayLister(client httpDoer, out io.Writer, namespace string, tags int) *quayLister {
+	return &quayLister{client: client, out: out, namespace: namespace, tags: tags}
+}
+
+func (l *quayLister) Next(ctx context.Context) ([]string, string, error) {
+	if l.done {
+		return nil, "", nil
+	}
+
+	query := url.Values{}
+	query.Set("namespace", l.namespace)
+	query.Set("public", "true")
+	if l.next != "" {
+		query.Set("next_page", l.next)
+	}
+
+	rsp, err := httpGet(ctx, l.client, l.out, "https://quay.io/api/v1/repository?"+query.Encode(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	var results struct {
+		Repositories []struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+		} `json:"repositories"`
+		NextPage string `json:"next_page"`
+	}
+	if err = decodeJSON(rsp, &results); err != nil {
+		return nil, "", err
+	}
+
+	var images []string
+	for _, repo := range results.Repositories {
+		tags := []string{"latest"}
+		if l.tags > 0 {
+			tags, err = l.recentTags(ctx, repo.Namespace, repo.Name)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+		for _, tag := range tags {
+			images = append(images, fmt.Sprintf("quay.io/%s/%s:%s", repo.Namespace, repo.Name, tag))
+		}
+	}
+	slices.Sort(images)
+
+	l.next = results.NextPage
+	l.done = l.next == ""
+	return images, l.next, nil
+}
+
+// recentTags returns the most recently pushed active tags of a repository
+func (l *quayLister) recentTags(ctx context.Context, namespace, name string) ([]string, error) {
+	rsp, err := httpGet(ctx, l.client, l.out, fmt.Sprintf("https://quay.io/api/v1/repository/%s/%s/tag/?onlyActiveTags=true&limit=%v", namespace, name, l.tags), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	var results struct {
+		Tags []struct {
+			Name string `json:"name"`
+		} `json:"tags"`
+	}
+	if err = decodeJSON(rsp, &results); err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for _, tag := range results.Tags {
+		tags = append(tags, tag.Name)
+	}
+	return tags, nil
+}
+
+// ghcrLister lists the :latest tag, or the most recent tags, of all container packages owned by a GitHub
+// organization, this uses the GitHub packages API, which requires a token with read:packages scope
+type ghcrLister struct {
+	client httpDoer
+	out    io.Writer
+	org    string
+	token  string
+	tags   int
+	next   string
+}
+
+func newGhcrLister(client httpDoer, out io.Writer, org, token string, tags int) *ghcrLister {
+	return &ghcrLister{
+		client: client,
+		out:    out,
+		org:    org,
+		token:  token,
+		tags:   tags,
+		next:   fmt.Sprintf("https://api.github.com/orgs/%s/packages?package_type=container&per_page=100", org),
+	}
+}
+
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+func (l *ghcrLister) Next(ctx context.Context) ([]string, string, error) {
+	if l.next == "" {
+		return nil, "", nil
+	}
+
+	rsp, err := httpGet(ctx, l.client, l.out, l.next, l.header())
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	var results []struct {
+		Name string `json:"name"`
+	}
+	if err = decodeJSON(rsp, &results); err != nil {
+		return nil, "", err
+	}
+
+	var images []string
+	for _, pkg := range results {
+		tags := []string{"latest"}
+		if l.tags > 0 {
+			tags, err = l.recentTags(ctx, pkg.Name)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+		for _, tag := range tags {
+			images = append(images, fmt.Sprintf("ghcr.io/%s/%s:%s", l.org, pkg.Name, tag))
+		}
+	}
+	slices.Sort(images)
+
+	// pagination is provided by the Link header
+	l.next = ""
+	if match := linkNextPattern.FindStringSubmatch(rsp.Header.Get("Link")); match != nil {
+		l.next = match[1]
+	}
+	return images, l.next, nil
+}
+
+const (
+	// defaultRetryAfter is the delay used when rate limited without a Retry-After header
+	defaultRetryAfter = 30 * time.Second
+	// maxListAttempts is the number of times a listing request is attempted before giving up
+	maxListAttempts = 5
+)
+
+// httpGet requests a URL, waiting and retrying when rate limited or the server is temporarily unavailable;
+// any response other than 200 OK is returned as an error, as is the context error once it is done
+func httpGet(ctx context.Context, client httpDoer, out io.Writer, url string, header http.Header) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		rsp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if rsp.StatusCode == http.StatusOK {
+			return rsp, nil
+		}
+		_ = rsp.Body.Close()
+
+		retryable := rsp.StatusCode == http.StatusTooManyRequests || rsp.StatusCode >= http.StatusInternalServerError
+		if !retryable || attempt >= maxListAttempts {
+			return nil, fmt.Errorf("unexpected status requesting %s: %s", url, rsp.Status)
+		}
+
+		delay := retryAfter(rsp.Header)
+		fmt.Fprintf(out, "%s requesting %s, retrying in %v\n", rsp.Status, url, delay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryAfter returns the delay from a Retry-After header, which may be either seconds or an HTTP date
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay
+		}
+	}
+	return defaultRetryAfter
+}
+
+func (l *ghcrLister) header() http.Header {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	if l.token != "" {
+		header.Set("Authorization", "Bearer "+l.token)
+	}
+	return header
+}
+
+// recentTags returns the tags of the most recent package versions, which are ordered newest first
+func (l *ghcrLister) recentTags(ctx context.Context, name string) ([]string, error) {
+	rsp, err := httpGet(ctx, l.client, l.out, fmt.Sprintf("https://api.github.com/orgs/%s/packages/container/%s/versions?per_page=%v", l.org, url.PathEscape(name), l.tags), l.header())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	var versions []struct {
+		Metadata struct {
+			Container struct {
+				Tags []string `json:"tags"`
+			} `json:"container"`
+		} `json:"metadata"`
+	}
+	if err = decodeJSON(rsp, &versions); err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for _, version := range versions {
+		for _, tag := range version.Metadata.Container.Tags {
+			if len(tags) < l.tags {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+// imagesFileIterator yields newline-delimited image references from a file, skipping blank lines and # comments; the
+// file is opened up front so a missing file is reported before scanning starts, and closed once iterated
+func imagesFileIterator(ctx context.Context, path string, defaultTag bool, out io.Writer) (func(func(int, string) bool), error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open images file: %w", err)
+	}
+	images := readerImagesIterator(ctx, file, defaultTag, out)
+	return func(f func(int, string) bool) {
+		defer func() { _ = file.Close() }()
+		images(f)
+	}, nil
+}
+
+// sliceImagesIterator yields the image references in order; when defaultTag is set, references without a tag are
+// scanned as :latest
+func sliceImagesIterator(images []string, defaultTag bool) func(func(int, string) bool) {
+	return func(f func(int, string) bool) {
+		for idx, image := range images {
+			if defaultTag {
+				image = withDefaultTag(image)
+			}
+			if !f(idx, image) {
+				return
+			}
+		}
+	}
+}
+
+// maxImageRefLine is the longest line of an images file or stdin, well beyond any valid reference so long paths of
+// non-image sources are read
+const maxImageRefLine = 1 << 20
+
+// readerImagesIterator yields image references line-by-line as they are read, skipping blank lines and # comments;
+// when defaultTag is set, references without a tag are scanned as :latest; no more are yielded once the context is
+// done, and enumeration stops with an error printed when the input can't be read
+func readerImagesIterator(ctx context.Context, reader io.Reader, defaultTag bool, out io.Writer) func(func(int, string) bool) {
+	idx := 0
+
+	return func(f func(int, string) bool) {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxImageRefLine)
+		for ctx.Err() == nil && scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if defaultTag {
+				line = withDefaultTag(line)
+			}
+			if !f(idx, line) {
+				return
+			}
+			idx++
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(out, "ERROR: unable to read images, stopping enumeration: %v\n", err)
+		}
+	}
+}
+
+// withDefaultTag appends :latest to an image reference which has neither a tag nor a digest
+func withDefaultTag(ref string) string {
+	name := ref[strings.LastIndex(ref, "/")+1:]
+	if strings.ContainsAny(name, ":@") {
+		return ref
+	}
+	return ref + ":latest"
+}
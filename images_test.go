@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// stubDoer responds with canned bodies by URL, and 404 Not Found for any other URL
+type stubDoer struct {
+	bodies   map[string]string
+	requests []string
+}
+
+func (s *stubDoer) Do(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	s.requests = append(s.requests, url)
+	body, ok := s.bodies[url]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     http.Header{},
+		Request:    req,
+	}, nil
+}
+
+func collect(images func(func(int, string) bool)) []string {
+	var out []string
+	for _, image := range images {
+		out = append(out, image)
+	}
+	return out
+}
+
+func TestDockerHubListerPagination(t *testing.T) {
+	const (
+		first  = "https://hub.docker.com/v2/repositories/library/?page_size=2"
+		second = "https://hub.docker.com/v2/repositories/library/?page=2&page_size=2"
+	)
+	client := &stubDoer{bodies: map[string]string{
+		first:  `{"next": "` + second + `", "results": [{"name": "ubuntu"}, {"name": "alpine"}]}`,
+		second: `{"next": "", "results": [{"name": "busybox"}]}`,
+	}}
+	lister := newDockerHubLister(client, io.Discard, "library", 0, dockerHubOptions{pageSize: 2})
+
+	got := collect(listerIterator(context.Background(), io.Discard, lister))
+	want := []string{"alpine:latest", "ubuntu:latest", "busybox:latest"}
+	if !slices.Equal(got, want) {
+		t.Errorf("images = %v, want %v", got, want)
+	}
+	if !slices.Equal(client.requests, []string{first, second}) {
+		t.Errorf("requests = %v, want the two pages in order", client.requests)
+	}
+}
+
+func TestDockerHubListerTags(t *testing.T) {
+	const first = "https://hub.docker.com/v2/repositories/anchore/?page_size=100"
+	client := &stubDoer{bodies: map[string]string{
+		first: `{"results": [{"name": "syft"}]}`,
+		"https://hub.docker.com/v2/repositories/anchore/syft/tags?page_size=2&ordering=last_updated": `{"results": [
+			{"name": "v1.1.0", "full_size": 1024},
+			{"name": "v1.0.0", "full_size": 4096}
+		]}`,
+	}}
+	lister := newDockerHubLister(client, io.Discard, "anchore", 2, dockerHubOptions{pageSize: 100, maxSize: 2048})
+
+	got := collect(listerIterator(context.Background(), io.Discard, lister))
+	want := []string{"anchore/syft:v1.1.0"}
+	if !slices.Equal(got, want) {
+		t.Errorf("images = %v, want %v, skipping the tag over the size limit", got, want)
+	}
+}
+
+func TestDockerHubListerError(t *testing.T) {
+	const first = "https://hub.docker.com/v2/repositories/library/?page_size=1"
+	client := &stubDoer{bodies: map[string]string{
+		first: `{"next": "https://hub.docker.com/v2/repositories/library/?page=2&page_size=1", "results": [{"name": "ubuntu"}]}`,
+	}}
+	lister := newDockerHubLister(client, io.Discard, "library", 0, dockerHubOptions{pageSize: 1})
+
+	images, _, err := lister.Next(context.Background())
+	if err != nil || !slices.Equal(images, []string{"ubuntu:latest"}) {
+		t.Fatalf("first page = %v, %v", images, err)
+	}
+	_, _, err = lister.Next(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "--start-url https://hub.docker.com/v2/repositories/library/?page=2&page_size=1") {
+		t.Errorf("error = %v, want the URL to resume listing from", err)
+	}
+}
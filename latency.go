@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+)
+
+// latencyPercentiles are the percentiles of scan durations printed at the end of a run
+var latencyPercentiles = []float64{50, 90, 95, 99}
+
+// latencyBuckets are the upper bounds of the scan duration histogram buckets, longer scans are counted in a final
+// bucket
+var latencyBuckets = []time.Duration{
+	time.Second,
+	5 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+}
+
+// latencyBarWidth is the width of the largest histogram bar
+const latencyBarWidth = 40
+
+// percentile returns the nearest-rank percentile p of the sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// printScanTimes prints percentiles and a histogram of the scan duration of each image
+func printScanTimes(w io.Writer, scanTimes map[string]time.Duration) {
+	durations := maps.Values(scanTimes)
+	if len(durations) == 0 {
+		return
+	}
+	slices.Sort(durations)
+
+	var parts []string
+	for _, p := range latencyPercentiles {
+		parts = append(parts, fmt.Sprintf("p%v %v", p, percentile(durations, p).Round(time.Millisecond)))
+	}
+	parts = append(parts, fmt.Sprintf("max %v", durations[len(durations)-1].Round(time.Millisecond)))
+	fmt.Fprintf(w, "scan durations of %v images: %s\n", len(durations), strings.Join(parts, "; "))
+
+	counts := make([]int, len(latencyBuckets)+1)
+	for _, d := range durations {
+		bucket := slices.IndexFunc(latencyBuckets, func(bound time.Duration) bool { return d < bound })
+		if bucket < 0 {
+			bucket = len(latencyBuckets)
+		}
+		counts[bucket]++
+	}
+	largest := slices.Max(counts)
+	for i, count := range counts {
+		label := fmt.Sprintf(">= %v", latencyBuckets[len(latencyBuckets)-1])
+		if i < len(latencyBuckets) {
+			label = fmt.Sprintf("< %v", latencyBuckets[i])
+		}
+		bar := strings.Repeat("#", (count*latencyBarWidth+largest-1)/largest)
+		fmt.Fprintf(w, "%v\t%v\t%s\n", label, count, bar)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kzantow-anchore/syft-unknown-analyzer/analyzer"
+	"github.com/sirupsen/logrus"
+
+	"github.com/anchore/go-logger"
+	logrusAdapter "github.com/anchore/go-logger/adapter/logrus"
+)
+
+// logFormats are the formats the log file may be written in
+var logFormats = []string{"text", "json"}
+
+// newLogger returns the logger of the run, which Syft also logs to, writing to the console at debug level and to the
+// log file when there is one; the returned func closes the log file
+func newLogger(cfg Config) (logger.Logger, func() error, error) {
+	l := logrus.New()
+	closer := func() error { return nil }
+	if cfg.LogFile != "" {
+		hook, err := newLogFileHook(cfg.LogFile, cfg.LogFormat)
+		if err != nil {
+			return nil, nil, err
+		}
+		l.AddHook(hook)
+		closer = hook.Close
+	}
+	log, err := logrusAdapter.Use(l, logrusAdapter.Config{
+		EnableConsole: true,
+		Level:         logger.DebugLevel,
+	})
+	if err != nil {
+		_ = closer()
+		return nil, nil, err
+	}
+	return log, closer, nil
+}
+
+// logFileHook writes every log entry to a file in its own format, so the console stays readable while the file may
+// be structured; entries are written unbuffered, so none are lost when a second signal terminates the run
+type logFileHook struct {
+	lock      sync.Mutex
+	file      *os.File
+	formatter logrus.Formatter
+}
+
+func newLogFileHook(path, format string) (*logFileHook, error) {
+	var formatter logrus.Formatter
+	switch format {
+	case "json":
+		formatter = logrusAdapter.DefaultJSONFormatter()
+	default:
+		formatter = &logrusAdapter.TextFormatter{
+			DisableColors:   true,
+			ForceFormatting: true,
+			FullTimestamp:   true,
+			TimestampFormat: time.RFC3339Nano,
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create log file: %w", err)
+	}
+	return &logFileHook{file: f, formatter: formatter}, nil
+}
+
+func (h *logFileHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logFileHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	// Syft may still log after the run closed the file
+	if h.file == nil {
+		return nil
+	}
+	_, err = h.file.Write(line)
+	return err
+}
+
+// Close closes the log file, entries logged afterward are only written to the console
+func (h *logFileHook) Close() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
+
+// workerIDs hands out the index of a free worker to each scan, there is one index for each image scanned
+// concurrently
+type workerIDs chan int
+
+func newWorkerIDs(parallelism int) workerIDs {
+	ids := make(workerIDs, parallelism)
+	for i := range parallelism {
+		ids <- i + 1
+	}
+	return ids
+}
+
+// withWorker takes a free worker index and returns a context logging with the worker, the image's scan key and the
+// run ID, and a func to release the worker once the scan is done
+func (s *scanner) withWorker(ctx context.Context, key string) (context.Context, func()) {
+	worker := <-s.workers
+	log := s.log.Nested("worker", worker, "image", key, "runId", s.cfg.RunID)
+	return analyzer.WithLogger(ctx, log), func() { s.workers <- worker }
+}
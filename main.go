@@ -3,267 +3,505 @@ package main
 import (
 	"cmp"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
-	"regexp"
 	"slices"
 	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
 
-	_ "github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/kzantow-anchore/syft-unknown-analyzer/analyzer"
+	"github.com/wagoodman/go-partybus"
 	"golang.org/x/exp/maps"
 
-	"github.com/anchore/go-logger"
-	"github.com/anchore/go-logger/adapter/logrus"
 	"github.com/anchore/go-sync"
 	"github.com/anchore/syft/syft"
-	"github.com/anchore/syft/syft/cataloging"
-	"github.com/anchore/syft/syft/cataloging/filecataloging"
-	"github.com/anchore/syft/syft/file"
-	"github.com/anchore/syft/syft/source"
 )
 
 func main() {
-	startAt := 0
-	count := 1000
-	parallelism := 4
-	enrich := true
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "help" {
+		printCommands()
+		return
+	}
 
-	ctx := context.Background()
-	executor := sync.NewExecutor(parallelism)
+	cmd, args, err := findCommand(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		printCommands()
+		os.Exit(2)
+	}
+	os.Exit(cmd.run(args))
+}
 
-	resultDir := "results"
-	if s, err := os.Stat(resultDir); err != nil || !s.IsDir() {
-		panicOnError(os.MkdirAll(resultDir, 0700|os.ModeDir))
+// analyze scans all images, returning an error if the run could not be started or any image failed
+func analyze(cfg Config) error {
+	stopProfiling, err := startProfiling(cfg)
+	if err != nil {
+		return err
 	}
+	defer stopProfiling()
 
-	// set Syft statics
-	syft.SetLogger(getOrPanic(logrus.New(logrus.Config{
-		EnableConsole: true,
-		Level:         logger.DebugLevel,
-	})))
+	cfg.RunID = uuid.NewString()
+	// the parent of date-stamped run directories
+	outDir := cfg.ResultDir
+	if cfg.DateDirs {
+		cfg.ResultDir = runDir(outDir, time.Now())
+	}
+	if cfg.EventsJSON {
+		cfg.Events = newJSONEventHandler(os.Stdout)
+		// keep stdout for events only
+		cfg.Output = os.Stderr
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	if cfg.Events == nil {
+		cfg.Events = newTextEventHandler(cfg.Output)
+	}
+	out := cfg.Output
 
-	startTime := time.Now()
-	scanTimes := map[string]time.Duration{}
+	filters, err := loadFilters(cfg)
+	if err != nil {
+		return err
+	}
 
-	// TODO check the total number of files
-	total := atomic.Int64{}
+	var db *resultDB
+	if cfg.DB != "" {
+		db, err = openResultDB(cfg.DB)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = db.Close() }()
+	}
 
-	providers := []string{"registry"} // or "docker", etc.
+	// in-flight scans use ctx, while dispatch is done once no new scans should start
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dispatch, stopDispatch := context.WithCancel(ctx)
+	defer stopDispatch()
+
+	// on the first signal stop dispatching new work and let in-flight scans finish, a second signal terminates
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		fmt.Fprintf(out, "received %v, waiting for in-flight scans to finish\n", sig)
+		stopDispatch()
+	}()
+
+	if cfg.ProfileMem && cfg.Parallelism > 1 {
+		fmt.Fprintf(out, "profiling memory, scanning one image at a time instead of %v\n", cfg.Parallelism)
+		cfg.Parallelism = 1
+	}
+	executor := sync.NewExecutor(cfg.Parallelism)
 
-	for idx, imageName := range imagesIterator() {
-		ref := imageName
+	resultDir := cfg.ResultDir
+	if s, err := os.Stat(resultDir); err != nil || !s.IsDir() {
+		if err := os.MkdirAll(resultDir, 0700|os.ModeDir); err != nil {
+			return fmt.Errorf("unable to create result directory: %w", err)
+		}
+	}
+	names, err := newResultNamer(resultDir, cfg.resultExt(), cfg.FilenameTemplate, cfg.RunID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !names.recognized() {
+		fmt.Fprintf(out, "WARNING: result files not named %s* are not read by baseline, since, diff, merge or report\n", resultFilePrefix)
+	}
 
-		if idx < startAt {
-			continue
+	completed, err := loadCheckpoint(resultDir, cfg.Resume)
+	if err != nil {
+		return err
+	}
+
+	var uploader *s3Uploader
+	if cfg.S3Bucket != "" {
+		if uploader, err = newS3Uploader(ctx, cfg.S3Bucket, cfg.S3Prefix, outDir); err != nil {
+			return err
 		}
-		if idx >= startAt+count {
-			break
+	}
+
+	var single *singleResultFile
+	if cfg.SingleFile != "" {
+		singleFile := cfg.SingleFile
+		if cfg.Gzip && !strings.HasSuffix(singleFile, ".gz") {
+			singleFile += ".gz"
 		}
-		executor.Execute(func() {
-			defer handlePanic()
-			fmt.Printf("Scanning: %v %s\n", idx, ref)
-			imageStartTime := time.Now()
-
-			src := getOrPanic(syft.GetSource(ctx, ref, syft.DefaultGetSourceConfig().
-				WithSources(providers...)))
-			defer func() { _ = src.Close() }()
-
-			fileCount := len(getOrPanic(getOrPanic(src.FileResolver(source.SquashedScope)).FilesByGlob("**/*")))
-			total.Add(int64(fileCount))
-
-			cfg := syft.DefaultCreateSBOMConfig().
-				WithUnknownsConfig(cataloging.UnknownsConfig{
-					RemoveWhenPackagesDefined:         false,
-					IncludeExecutablesWithoutPackages: true,
-					IncludeUnexpandedArchives:         true,
-				}).
-				// disable file hashing
-				WithFilesConfig(filecataloging.DefaultConfig().WithHashers())
-
-			if enrich {
-				cfg = cfg.WithPackagesConfig(cfg.Packages.
-					WithJavascriptConfig(cfg.Packages.JavaScript.WithSearchRemoteLicenses(true)).
-					WithJavaArchiveConfig(cfg.Packages.JavaArchive.WithUseNetwork(true)).
-					WithGolangConfig(cfg.Packages.Golang.WithSearchRemoteLicenses(true)),
-				)
-			}
+		if single, err = openSingleResultFile(singleFile, cfg.Format, cfg.resultColumns(), cfg.separator(), cfg.Resume || cfg.Append); err != nil {
+			return fmt.Errorf("unable to open single result file: %w", err)
+		}
+		single.dedup = cfg.Dedup
+	}
 
-			sbom := getOrPanic(syft.CreateSBOM(ctx, src, cfg))
+	failures, previousFailures, err := openFailureLog(resultDir)
+	if err != nil {
+		return err
+	}
+	if len(previousFailures) > 0 && !cfg.RetryFailures {
+		fmt.Fprintf(out, "WARNING: replacing %v failures of an earlier run, use --retry-failures to retry them\n", len(previousFailures))
+	}
+	defer func() { _ = failures.Close() }()
 
-			// ignore unknowns we don't care about, since we are not removing unknowns with packages
-			filterUnknowns(sbom.Artifacts.Unknowns)
+	log, closeLog, err := newLogger(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create logger: %w", err)
+	}
+	defer func() {
+		if err := closeLog(); err != nil {
+			fmt.Fprintf(out, "ERROR: unable to close log file: %v\n", err)
+		}
+	}()
+	// set Syft statics
+	syft.SetLogger(log)
+	var catalogers *catalogerTimer
+	if cfg.ProfileCatalogers {
+		bus := partybus.NewBus()
+		syft.SetBus(bus)
+		catalogers = newCatalogerTimer(bus)
+	}
 
-			safeRef := regexp.MustCompile(`[^a-zA-Z0-9]`).ReplaceAllString(ref, "_")
-			resultFilePath := filepath.Join(resultDir, fmt.Sprintf("unknowns-%s.csv", safeRef))
-			_ = os.Remove(resultFilePath)
+	analyzerCfg, err := cfg.analyzerConfig(filters)
+	if err != nil {
+		return err
+	}
 
-			unknownMap := sbom.Artifacts.Unknowns
-			if len(unknownMap) == 0 {
-				return
-			}
+	var base *baseline
+	if cfg.Baseline != "" {
+		if base, err = loadBaseline(cfg.Baseline, cfg.Fingerprint); err != nil {
+			return err
+		}
+	}
 
-			f := getOrPanic(os.OpenFile(resultFilePath, os.O_CREATE|os.O_RDWR, 0600))
-			defer func() { _ = f.Close() }()
-			writeLn := func(line string, args ...any) {
-				_ = getOrPanic(fmt.Fprintf(f, line, args...))
-				_ = getOrPanic(fmt.Fprintln(f))
-			}
+	var since *sinceRun
+	if cfg.Since != "" {
+		if since, err = loadSinceRun(cfg.Since); err != nil {
+			return err
+		}
+	}
 
-			keys := maps.Keys(unknownMap)
-			slices.SortFunc(keys, func(a, b file.Coordinates) int {
-				return strings.Compare(a.RealPath, b.RealPath)
-			})
+	m := newMetrics()
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		if metricsServer, err = startMetricsServer(cfg.MetricsAddr, m, out); err != nil {
+			return err
+		}
+	}
 
-			writeLn(`"IMAGE","FILE","TASK",ERROR"`)
-			for _, coord := range keys {
-				errs := unknownMap[coord]
-				for _, err := range errs {
-					parts := strings.SplitN(err, ": ", 2)
-					tsk := ""
-					if len(parts) > 1 {
-						tsk = parts[0]
-						err = parts[1]
-					}
-					writeLn(`"%s","%s","%s","%s"`, escapeQuotedCsv(ref), escapeQuotedCsv(coord.RealPath), escapeQuotedCsv(tsk), escapeQuotedCsv(err))
-				}
-			}
+	startTime := time.Now()
+	if cfg.Deadline > 0 {
+		var cancelDeadline context.CancelFunc
+		dispatch, cancelDeadline = context.WithTimeout(dispatch, cfg.Deadline)
+		defer cancelDeadline()
+	}
 
-			scanTime := time.Now().Sub(imageStartTime)
-			scanTimes[ref] = scanTime
-			fmt.Printf("completed %v '%v' in %v\n", idx, ref, scanTime)
+	var sbomW *sbomWriter
+	if cfg.EmitSBOM != "" {
+		if sbomW, err = newSBOMWriter(cfg.EmitSBOM); err != nil {
+			return err
+		}
+	}
 
-			if providers[0] == "docker" {
-				img := getOrPanic(run("docker", "image", "list", "-aq", "-f", "reference="+ref))
-				img = strings.TrimSpace(img)
-				_ = getOrPanic(run("docker", "rmi", "-f", img))
-			}
-		})
+	s := &scanner{
+		cfg:         cfg,
+		analyzerCfg: analyzerCfg,
+		sink:        newSink(cfg, names, single, db),
+		completed:   completed,
+		agg:         newAggregator(cfg),
+		metrics:     m,
+		baseline:    base,
+		since:       since,
+		memory:      newMemoryGovernor(cfg.MaxMemory, out),
+		single:      single,
+		names:       names,
+		uploader:    uploader,
+		sbom:        sbomW,
+		log:         log,
+		workers:     newWorkerIDs(cfg.Parallelism),
 	}
 
-	executor.Wait()
+	// dangling images are only removed when they were left by this run's pulls
+	var dangling map[string]struct{}
+	if s.cleanupDocker() {
+		if dangling, err = danglingDockerImages(); err != nil {
+			fmt.Fprintf(out, "WARNING: dangling docker images won't be removed: %v\n", err)
+		}
+	}
 
-	for ref, duration := range sorted(scanTimes) {
-		fmt.Printf("%v\t%v\n", ref, duration)
+	completedCount := atomic.Int64{}
+	skippedCount := atomic.Int64{}
+	missingCount := atomic.Int64{}
+	failedCount := atomic.Int64{}
+	// aborted is set once max-failures is reached
+	aborted := atomic.Bool{}
+	// remainingCount are the images which were dispatched but not started before dispatch was done
+	remainingCount := atomic.Int64{}
+
+	imageSource := cfg.ImageSource
+	if imageSource == nil {
+		imageSource = imagesIterator
 	}
-	fmt.Printf("all completed in %v; total files scanned: %v\n", time.Now().Sub(startTime), total.Load())
-}
+	images, err := imageSource(dispatch, cfg)
+	if err != nil {
+		return err
+	}
+	for idx, imageName := range images {
+		ref := imageName
 
-func sorted[K cmp.Ordered, V any](values map[K]V) func(func(K, V) bool) {
-	keys := maps.Keys(values)
-	slices.Sort(keys)
-	return func(f func(K, V) bool) {
-		for _, key := range keys {
-			if !f(key, values[key]) {
-				return
+		if dispatch.Err() != nil {
+			break
+		}
+		if idx < cfg.StartAt {
+			continue
+		}
+		if cfg.Count > 0 && idx >= cfg.StartAt+cfg.Count {
+			break
+		}
+		for _, platform := range cfg.platforms() {
+			key := scanKey(ref, platform)
+			if completed.isComplete(key) {
+				cfg.Events(Event{Type: eventSkipped, Ref: key, Index: idx})
+				skippedCount.Add(1)
+				continue
+			}
+			if cfg.SkipExisting && s.hasResult(key, ref) {
+				cfg.Events(Event{Type: eventSkipped, Ref: key, Index: idx, Err: errResultExists})
+				skippedCount.Add(1)
+				continue
 			}
+			executor.Execute(func() {
+				defer s.memory.acquire(dispatch)()
+				if dispatch.Err() != nil {
+					skippedCount.Add(1)
+					remainingCount.Add(1)
+					return
+				}
+				ctx, release := s.withWorker(ctx, key)
+				defer release()
+				err := s.scanImage(ctx, idx, ref, platform)
+				if errors.Is(err, analyzer.ErrImageTooLarge) {
+					cfg.Events(Event{Type: eventSkipped, Ref: key, Index: idx, Err: err})
+					skippedCount.Add(1)
+					return
+				}
+				if errors.Is(err, analyzer.ErrImageNotFound) {
+					cfg.Events(Event{Type: eventMissing, Ref: key, Index: idx, Err: err})
+					missingCount.Add(1)
+					return
+				}
+				if err != nil {
+					cfg.Events(Event{Type: eventFailed, Ref: key, Index: idx, Err: err})
+					m.imageFailed()
+					failure := imageFailure{idx: idx, ref: ref, platform: platform, err: err}
+					if err := failures.record(failure); err != nil {
+						fmt.Fprintf(out, "ERROR: unable to record failure: %v\n", err)
+					}
+					if cfg.MaxFailures > 0 && failedCount.Add(1) == int64(cfg.MaxFailures) {
+						fmt.Fprintf(out, "aborting: too many failures, %v images failed; waiting for in-flight scans to finish\n", cfg.MaxFailures)
+						aborted.Store(true)
+						stopDispatch()
+					}
+					return
+				}
+				completedCount.Add(1)
+			})
 		}
 	}
-}
 
-func escapeQuotedCsv(value string) string {
-	return strings.ReplaceAll(value, "\"", "\"\"")
-}
+	executor.Wait()
 
-func imagesIterator() func(func(int, string) bool) {
-	if true {
-		return dockerOfficialImagesIterator()
+	if cfg.RetryFailures && dispatch.Err() == nil {
+		retryFailures(ctx, dispatch, executor, s, failures, previousFailures, &completedCount, &missingCount)
 	}
-	return imagesTagsIterator("anchore", "test_images")
-}
-
-func imagesTagsIterator(org, name string) func(func(int, string) bool) {
-	idx := 0
+	if dangling != nil {
+		removed, err := removeDanglingDockerImages(dangling)
+		if err != nil {
+			fmt.Fprintf(out, "ERROR: %v\n", err)
+		} else if removed > 0 {
+			fmt.Fprintf(out, "removed %v dangling docker images\n", removed)
+		}
+	}
+	if catalogers != nil {
+		catalogers.stop()
+	}
+	if cfg.MemProfile != "" {
+		if err = writeMemProfile(cfg.MemProfile); err != nil {
+			fmt.Fprintf(out, "ERROR: unable to write memory profile: %v\n", err)
+		}
+	}
+	timeBoxed := errors.Is(dispatch.Err(), context.DeadlineExceeded)
+	interrupted := errors.Is(dispatch.Err(), context.Canceled)
 
-	return func(f func(int, string) bool) {
-		next := fmt.Sprintf("https://hub.docker.com/v2/namespaces/%s/repositories/%s/tags?page_size=100", org, name)
-		for {
-			var tags []string
-			tags, next = getNameList(next)
-			for _, tag := range tags {
-				if !f(idx, fmt.Sprintf("%s/%s:%s", org, name, tag)) {
-					return
-				}
-				idx++
-			}
-			if next == "" {
-				return
-			}
+	if metricsServer != nil {
+		stopMetricsServer(metricsServer, out)
+	}
+	if err = s.sink.Close(); err != nil {
+		fmt.Fprintf(out, "ERROR: unable to write results: %v\n", err)
+	} else if single != nil && uploader != nil {
+		if err = uploader.upload(context.Background(), single.path); err != nil {
+			fmt.Fprintf(out, "ERROR: %v\n", err)
 		}
 	}
-}
 
-func dockerOfficialImagesIterator() func(func(int, string) bool) {
-	idx := 0
+	results := s.agg.Snapshot()
+	if err = writeSummary(resultDir, results.Summaries, cfg.SummaryJSON); err != nil {
+		fmt.Fprintf(out, "ERROR: %v\n", err)
+	}
+	metadata := newRunMetadata(cfg, startTime, time.Since(startTime))
+	metadata.Digests = results.Digests
+	clean := cleanImages(results.Summaries)
+	metadata.Scanned, metadata.Clean = len(results.Summaries), len(clean)
+	metadata.Packages = results.Packages
+	metadata.Missing, metadata.Failed = missingCount.Load(), len(failures.list())
+	metadata.Aborted = aborted.Load()
+	if cfg.ListClean {
+		metadata.CleanImages = clean
+	}
+	if timeBoxed {
+		metadata.TimeBoxed = true
+		metadata.Remaining = remainingCount.Load()
+	}
+	if err = writeRunMetadata(resultDir, metadata); err != nil {
+		fmt.Fprintf(out, "ERROR: unable to write run metadata: %v\n", err)
+	}
 
-	return func(f func(int, string) bool) {
-		next := "https://hub.docker.com/v2/repositories/library/?page_size=100"
-		for {
-			var images []string
-			images, next = getNameList(next)
-			for _, image := range images {
-				if !f(idx, image+":latest") {
-					return
-				}
-				idx++
-			}
-			if next == "" {
-				return
-			}
+	printScanTimes(out, results.ScanTimes)
+	fmt.Fprintf(out, "all completed in %v; total files scanned: %v; packages found: %v\n", time.Now().Sub(startTime),
+		results.Files, results.Packages)
+	fmt.Fprintf(out, "images completed: %v; missing: %v; failed: %v; skipped: %v\n", completedCount.Load(),
+		missingCount.Load(), len(failures.list()), skippedCount.Load())
+	if timeBoxed {
+		fmt.Fprintf(out, "deadline of %v reached: %v images scanned; %v remaining, and any not yet listed\n", cfg.Deadline,
+			len(results.Summaries), remainingCount.Load())
+	}
+	printCleanImages(out, results.Summaries, cfg.ListClean)
+	printStageTimes(out, results.Summaries)
+	printPeakMemory(out, results.Summaries, peakMemoryImages)
+	if catalogers != nil {
+		catalogers.print(out)
+	}
+	if !cfg.NoHash {
+		fmt.Fprintf(out, "time spent hashing files with unknowns: %v (disable with --no-hash); syft file hashers: %v\n",
+			results.HashTime, cfg.Hashers)
+	}
+	filters.printTally(out)
+	printEntries(out, s.agg.tasks.title(), results.Tasks)
+	printEntries(out, s.agg.dirs.title, topEntries(results.Dirs, cfg.TopDirs))
+	printEntries(out, s.agg.extensions.title, topEntries(results.Extensions, cfg.TopExtensions))
+	printEntries(out, s.agg.layers.title, topEntries(results.Layers, cfg.TopLayers))
+	if cfg.RatioThreshold > 0 {
+		printHighRatio(out, results.Summaries, cfg.RatioThreshold)
+	}
+
+	if since != nil {
+		since.print(out)
+	}
+
+	if cfg.Keep > 0 && !interrupted {
+		removed, err := pruneRunDirs(outDir, cfg.Keep, resultDir)
+		for _, dir := range removed {
+			fmt.Fprintf(out, "removed old run directory: %s\n", dir)
+		}
+		if err != nil {
+			fmt.Fprintf(out, "ERROR: %v\n", err)
 		}
 	}
-}
 
-func filterUnknowns(unknowns map[file.Coordinates][]string) {
-	for k, errs := range unknowns {
-		errs = filterErrs(errs)
-		if len(errs) == 0 {
-			delete(unknowns, k)
-		} else {
-			unknowns[k] = errs
+	var regressionErr error
+	if base != nil {
+		fixed := base.fixedRows()
+		if err = writeResultFile(filepath.Join(resultDir, "fixed.csv"), "csv", fixed, optionalColumns{}, ','); err != nil {
+			fmt.Fprintf(out, "ERROR: unable to write fixed unknowns: %v\n", err)
+		}
+		added := base.newCount()
+		fmt.Fprintf(out, "unknowns since baseline: %v new; %v fixed\n", added, len(fixed))
+		if cfg.RegressionThreshold >= 0 && added > cfg.RegressionThreshold {
+			regressionErr = fmt.Errorf("%v new unknowns exceed the regression threshold of %v", added, cfg.RegressionThreshold)
 		}
 	}
+
+	failed := failures.list()
+	if len(failed) == 0 {
+		return regressionErr
+	}
+	fmt.Fprintln(out, "failures:")
+	for _, f := range failed {
+		fmt.Fprintf(out, "%v\t%v\n", f.key(), f.err)
+	}
+	if aborted.Load() {
+		return errors.Join(regressionErr, fmt.Errorf("aborted: %v images failed, reaching max-failures", len(failed)))
+	}
+	return errors.Join(regressionErr, fmt.Errorf("%v images failed", len(failed)))
 }
 
-func filterErrs(errs []string) []string {
-	var out []string
-	for _, err := range errs {
-		if strings.Contains(err, "unable to determine ELF features") {
+// retryFailures scans each failed image once more, removing those which succeed from the failure log; this includes
+// the previous failures of an earlier run which were not scanned successfully in this one
+func retryFailures(ctx, dispatch context.Context, executor sync.Executor, s *scanner, failures *failureLog, previous []imageFailure, completedCount, missingCount *atomic.Int64) {
+	failed := failures.list()
+	retried := map[string]struct{}{}
+	for _, f := range failed {
+		retried[f.key()] = struct{}{}
+	}
+	for _, f := range previous {
+		if _, ok := retried[f.key()]; ok || s.completed.isComplete(f.key()) {
 			continue
 		}
-		out = append(out, err)
+		retried[f.key()] = struct{}{}
+		failed = append(failed, f)
 	}
-	return out
-}
+	if len(failed) == 0 {
+		return
+	}
+	fmt.Fprintf(s.cfg.Output, "retrying %v failed images\n", len(failed))
 
-func handlePanic() {
-	if err := recover(); err != nil {
-		fmt.Printf("ERROR: %v\n", err)
+	for _, f := range failed {
+		executor.Execute(func() {
+			defer s.memory.acquire(dispatch)()
+			if dispatch.Err() != nil {
+				return
+			}
+			ctx, release := s.withWorker(ctx, f.key())
+			defer release()
+			err := s.scanImage(ctx, f.idx, f.ref, f.platform)
+			switch {
+			case errors.Is(err, analyzer.ErrImageTooLarge):
+				s.cfg.Events(Event{Type: eventSkipped, Ref: f.key(), Index: f.idx, Err: err})
+				err = nil
+			case errors.Is(err, analyzer.ErrImageNotFound):
+				s.cfg.Events(Event{Type: eventMissing, Ref: f.key(), Index: f.idx, Err: err})
+				missingCount.Add(1)
+				err = nil
+			case err != nil:
+				s.cfg.Events(Event{Type: eventFailed, Ref: f.key(), Index: f.idx, Err: err})
+			default:
+				completedCount.Add(1)
+			}
+			if err := failures.resolve(f, err); err != nil {
+				fmt.Fprintf(s.cfg.Output, "ERROR: unable to update failures: %v\n", err)
+			}
+		})
 	}
+	executor.Wait()
 }
 
-func getNameList(url string) ([]string, string) {
-	rsp := getOrPanic(http.Get(url))
-	defer func() { _ = rsp.Body.Close() }()
-
-	var results map[string]any
-	panicOnError(json.Unmarshal(getOrPanic(io.ReadAll(rsp.Body)), &results))
-
-	next, _ := results["next"].(string)
-
-	var names []string
-	for _, result := range results["results"].([]any) {
-		result := result.(map[string]any)
-		names = append(names, result["name"].(string))
+func sorted[K cmp.Ordered, V any](values map[K]V) func(func(K, V) bool) {
+	keys := maps.Keys(values)
+	slices.Sort(keys)
+	return func(f func(K, V) bool) {
+		for _, key := range keys {
+			if !f(key, values[key]) {
+				return
+			}
+		}
 	}
-	slices.Sort(names)
-	return names, next
 }
 
 func run(command ...string) (string, error) {
@@ -271,14 +509,3 @@ func run(command ...string) (string, error) {
 	out, err := cmd.CombinedOutput()
 	return string(out), err
 }
-
-func getOrPanic[T any](value T, err error) T {
-	panicOnError(err)
-	return value
-}
-
-func panicOnError(err error) {
-	if err != nil {
-		panic(err)
-	}
-}
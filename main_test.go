@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/source"
+	"github.com/anchore/syft/syft/source/directorysource"
+)
+
+func TestAnalyzeScanLoop(t *testing.T) {
+	fixture := t.TempDir()
+	// an archive which can't be read is reported as an unknown by the java cataloger
+	if err := os.WriteFile(filepath.Join(fixture, "app.jar"), []byte("not a zip"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.ResultDir = t.TempDir()
+	cfg.Parallelism = 2
+	cfg.Output = io.Discard
+	cfg.ImageSource = func(context.Context, Config) (func(func(int, string) bool), error) {
+		return sliceImagesIterator([]string{"example/app:1", "example/missing:1", "example/denied:1"}, false), nil
+	}
+	cfg.GetSource = func(_ context.Context, ref string, _ *syft.GetSourceConfig) (source.Source, error) {
+		switch ref {
+		case "example/missing:1":
+			return nil, errors.New("MANIFEST_UNKNOWN: manifest unknown")
+		case "example/denied:1":
+			return nil, errors.New("DENIED: requested access to the resource is denied")
+		}
+		return directorysource.NewFromPath(fixture)
+	}
+	lock := sync.Mutex{}
+	events := map[string]string{}
+	cfg.Events = func(e Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		events[e.Ref] = e.Type
+	}
+
+	err := analyze(cfg)
+	if err == nil || !strings.Contains(err.Error(), "1 images failed") {
+		t.Errorf("analyze() = %v, want the denied image to fail", err)
+	}
+
+	wantEvents := map[string]string{
+		"example/app:1":     eventCompleted,
+		"example/missing:1": eventMissing,
+		"example/denied:1":  eventFailed,
+	}
+	for ref, want := range wantEvents {
+		if got := events[ref]; got != want {
+			t.Errorf("last event of %s = %q, want %q", ref, got, want)
+		}
+	}
+
+	results, err := readResultDir(cfg.ResultDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results = %v, want only example/app:1", results)
+	}
+	found := false
+	for _, row := range results["example/app:1"] {
+		found = found || (strings.HasSuffix(row.File, "app.jar") && strings.Contains(row.Error, "not a valid zip file"))
+	}
+	if !found {
+		t.Errorf("rows = %+v, want the unreadable archive", results["example/app:1"])
+	}
+
+	failures, err := readFailures(filepath.Join(cfg.ResultDir, failuresFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures[0].ref != "example/denied:1" {
+		t.Errorf("failures = %+v, want example/denied:1", failures)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	runtimeMetrics "runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryPollInterval is how often a paused scan checks whether memory has recovered
+const memoryPollInterval = time.Second
+
+// memoryGovernor pauses starting new scans while the resident memory of the process is over a limit, so several
+// large images scanning at once don't exhaust memory; a scan always starts when no other scan is running
+type memoryGovernor struct {
+	limit uint64
+	rss   func() uint64
+	out   io.Writer
+
+	lock    sync.Mutex
+	running int
+	paused  bool
+}
+
+// newMemoryGovernor returns a governor limiting resident memory to limitMiB, nil when there is no limit
+func newMemoryGovernor(limitMiB int, out io.Writer) *memoryGovernor {
+	if limitMiB <= 0 {
+		return nil
+	}
+	return &memoryGovernor{limit: uint64(limitMiB) << 20, rss: residentMemory, out: out}
+}
+
+// acquire blocks until memory is under the limit, or the context is done, and returns a func to call when the scan
+// is finished; a nil governor never blocks
+func (g *memoryGovernor) acquire(ctx context.Context) (release func()) {
+	if g == nil {
+		return func() {}
+	}
+	for !g.tryAcquire() {
+		select {
+		case <-ctx.Done():
+			return func() {}
+		case <-time.After(memoryPollInterval):
+		}
+	}
+	return func() {
+		g.lock.Lock()
+		defer g.lock.Unlock()
+		g.running--
+	}
+}
+
+func (g *memoryGovernor) tryAcquire() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	rss := g.rss()
+	if g.running > 0 && rss > g.limit {
+		if !g.paused {
+			g.paused = true
+			fmt.Fprintf(g.out, "memory %v MiB over %v MiB limit, pausing new scans with %v running\n", rss>>20, g.limit>>20, g.running)
+		}
+		return false
+	}
+	if g.paused {
+		g.paused = false
+		fmt.Fprintf(g.out, "resuming scans with %v running, memory %v MiB\n", g.running, rss>>20)
+	}
+	g.running++
+	return true
+}
+
+// residentMemory returns the resident set size of the process, on platforms without /proc this falls back to the
+// memory obtained from the OS by the Go runtime
+func residentMemory() uint64 {
+	if statm, err := os.ReadFile("/proc/self/statm"); err == nil {
+		fields := strings.Fields(string(statm))
+		if len(fields) > 1 {
+			if pages, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				return pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+
+	sample := []runtimeMetrics.Sample{{Name: "/memory/classes/total:bytes"}}
+	runtimeMetrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// mergeConfig holds the parameters of the merge command
+type mergeConfig struct {
+	// Inputs are the result directories to merge, in order of precedence
+	Inputs []string
+	// OutputDir is the directory the merged results are written to
+	OutputDir string
+	// Format is the merged result file format, one of: csv, json, tsv
+	Format string
+	// Gzip compresses the merged result files
+	Gzip bool
+	// Output receives the warnings and the summary of the merge, by default stdout
+	Output io.Writer
+}
+
+func parseMergeConfig(args []string) (mergeConfig, error) {
+	cfg := mergeConfig{Format: "csv", Output: os.Stdout}
+
+	fs := flag.NewFlagSet("analyzer merge", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: analyzer merge [flags] <result-dir>...\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.OutputDir, "o", cfg.OutputDir, "directory to write the merged results to")
+	fs.StringVar(&cfg.Format, "format", cfg.Format, "merged result file format: "+strings.Join(resultFormats, ", "))
+	fs.BoolVar(&cfg.Gzip, "gzip", cfg.Gzip, "gzip compress merged result files, appending .gz to their names")
+
+	inputs, err := parseInterspersed(fs, args)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Inputs = inputs
+
+	switch {
+	case len(cfg.Inputs) == 0:
+		return cfg, fmt.Errorf("at least one result directory to merge is required")
+	case cfg.OutputDir == "":
+		return cfg, fmt.Errorf("an output directory is required, use -o")
+	case !slices.Contains(resultFormats, cfg.Format):
+		return cfg, fmt.Errorf("invalid format: %s, must be one of: %s", cfg.Format, strings.Join(resultFormats, ", "))
+	}
+	for _, input := range cfg.Inputs {
+		if s, err := os.Stat(input); err != nil || !s.IsDir() {
+			return cfg, fmt.Errorf("not a result directory: %s", input)
+		}
+		if absPath(input) == absPath(cfg.OutputDir) {
+			return cfg, fmt.Errorf("the output directory can't be one of the inputs: %s", input)
+		}
+	}
+	return cfg, nil
+}
+
+// parseInterspersed parses flags which may appear before, between or after the positional arguments, which are
+// returned
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func absPath(path string) string {
+	if p, err := filepath.Abs(path); err == nil {
+		return p
+	}
+	return path
+}
+
+// merge combines the result files and summaries of result directories, such as those of a run sharded across
+// machines; an image in more than one input is only taken from the first, with a warning
+func merge(cfg mergeConfig) error {
+	if err := os.MkdirAll(cfg.OutputDir, 0700|os.ModeDir); err != nil {
+		return err
+	}
+
+	ext := cfg.Format
+	if cfg.Gzip {
+		ext += ".gz"
+	}
+
+	source := map[string]string{}
+	merged, rowCount := 0, 0
+	for _, input := range cfg.Inputs {
+		byImage, err := readResultDir(input)
+		if err != nil {
+			return err
+		}
+
+		for key, rows := range sorted(byImage) {
+			if previous, ok := source[key]; ok {
+				fmt.Fprintf(cfg.Output, "WARNING: %s is in both %s and %s, using %s\n", key, previous, input, previous)
+				continue
+			}
+			source[key] = input
+
+			columns := optionalColumns{}
+			for _, row := range rows {
+				columns.fingerprint = columns.fingerprint || row.Fingerprint != ""
+				columns.count = columns.count || row.Count > 0
+				columns.digest = columns.digest || row.Pinned != nil
+				columns.hint = columns.hint || row.Hint != ""
+			}
+			if err = writeResultFile(resultFilePath(cfg.OutputDir, key, ext), cfg.Format, rows, columns, defaultSeparator(cfg.Format)); err != nil {
+				return fmt.Errorf("unable to write merged results: %w", err)
+			}
+			merged++
+			rowCount += len(rows)
+		}
+	}
+
+	summaries, err := mergeSummaries(cfg.Output, cfg.Inputs, source)
+	if err != nil {
+		return err
+	}
+	if err = writeSummary(cfg.OutputDir, summaries, false); err != nil {
+		return err
+	}
+
+	files, packages, unknowns := 0, 0, 0
+	for _, s := range summaries {
+		files += s.Files
+		packages += s.Packages
+		unknowns += s.Unknowns
+	}
+	fmt.Fprintf(cfg.Output, "merged %v inputs: %v images with unknowns; %v rows\n", len(cfg.Inputs), merged, rowCount)
+	fmt.Fprintf(cfg.Output, "images: %v; files: %v; packages: %v; files with unknowns: %v\n", len(summaries), files, packages, unknowns)
+	return nil
+}
+
+// mergeSummaries reads the summary of each input, an image summarized in more than one input is taken from the
+// input its result file was taken from, otherwise the first
+func mergeSummaries(w io.Writer, inputs []string, source map[string]string) ([]imageSummary, error) {
+	var out []imageSummary
+	seen := map[string]string{}
+	for _, input := range inputs {
+		summaries, err := readSummaryCSV(filepath.Join(input, "summary.csv"))
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(w, "WARNING: no summary in %s\n", input)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range summaries {
+			key := scanKey(s.Image, s.Platform)
+			if from, ok := source[key]; ok && from != input {
+				continue
+			}
+			if previous, ok := seen[key]; ok {
+				if _, hasResults := source[key]; !hasResults {
+					fmt.Fprintf(w, "WARNING: %s is in both %s and %s, using %s\n", key, previous, input, previous)
+				}
+				continue
+			}
+			seen[key] = input
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
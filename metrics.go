@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// scanDurationBuckets are the upper bounds, in seconds, of the image scan duration histogram
+var scanDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800}
+
+// metrics are run totals exposed in the Prometheus text format, updated as each image completes
+type metrics struct {
+	scanned  atomic.Int64
+	failed   atomic.Int64
+	files    atomic.Int64
+	unknowns atomic.Int64
+
+	lock          sync.Mutex
+	bucketCounts  []int64
+	durationSum   float64
+	durationCount int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{bucketCounts: make([]int64, len(scanDurationBuckets))}
+}
+
+// imageScanned records an image which was scanned successfully
+func (m *metrics) imageScanned(files, unknowns int, duration time.Duration) {
+	m.scanned.Add(1)
+	m.files.Add(int64(files))
+	m.unknowns.Add(int64(unknowns))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	seconds := duration.Seconds()
+	for i, bound := range scanDurationBuckets {
+		if seconds <= bound {
+			m.bucketCounts[i]++
+		}
+	}
+	m.durationSum += seconds
+	m.durationCount++
+}
+
+func (m *metrics) imageFailed() {
+	m.failed.Add(1)
+}
+
+func (m *metrics) write(w io.Writer) {
+	counter := func(name, help string, value int64) {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+	}
+	counter("analyzer_images_scanned_total", "Images scanned successfully.", m.scanned.Load())
+	counter("analyzer_images_failed_total", "Images which failed to scan.", m.failed.Load())
+	counter("analyzer_files_total", "Files in all scanned images.", m.files.Load())
+	counter("analyzer_unknowns_total", "Unknown errors reported in all scanned images.", m.unknowns.Load())
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	const name = "analyzer_image_scan_duration_seconds"
+	_, _ = fmt.Fprintf(w, "# HELP %s Time taken to scan each image.\n# TYPE %s histogram\n", name, name)
+	for i, bound := range scanDurationBuckets {
+		_, _ = fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, strconv.FormatFloat(bound, 'f', -1, 64), m.bucketCounts[i])
+	}
+	_, _ = fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, m.durationCount)
+	_, _ = fmt.Fprintf(w, "%s_sum %v\n%s_count %d\n", name, m.durationSum, name, m.durationCount)
+}
+
+// startMetricsServer serves the metrics on /metrics in the background, the listener is opened before returning so
+// an unusable address is reported at startup
+func startMetricsServer(addr string, m *metrics, out io.Writer) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start metrics server: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.write(w)
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(out, "ERROR: metrics server stopped: %v\n", err)
+		}
+	}()
+	return srv, nil
+}
+
+// stopMetricsServer shuts the server down, waiting briefly for in-flight scrapes
+func stopMetricsServer(srv *http.Server, out io.Writer) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		fmt.Fprintf(out, "ERROR: unable to stop metrics server: %v\n", err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// imagePattern matches image references by a glob, or a regular expression when prefixed with "re:"
+type imagePattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+// newImagePatterns parses include or exclude patterns, reporting invalid globs and regular expressions
+func newImagePatterns(patterns []string) ([]imagePattern, error) {
+	var out []imagePattern
+	for _, pattern := range patterns {
+		if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+			regex, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid image pattern %q: %w", pattern, err)
+			}
+			out = append(out, imagePattern{regex: regex})
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid image pattern %q: %w", pattern, err)
+		}
+		out = append(out, imagePattern{glob: pattern})
+	}
+	return out, nil
+}
+
+// matches returns true when a regex matches the ref, or a glob matches its repository name, e.g. python* matches
+// docker.io/library/python:3.12; a glob with a slash matches the repository path, e.g. bitnami/*
+func (p imagePattern) matches(ref string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(ref)
+	}
+	name := repositoryPath(ref)
+	if !strings.Contains(p.glob, "/") {
+		name = path.Base(name)
+	}
+	matched, _ := path.Match(p.glob, name)
+	return matched
+}
+
+// imageNameFilter selects images matching any include pattern, or any image when there are none, and not matching
+// an exclude pattern
+type imageNameFilter struct {
+	include []imagePattern
+	exclude []imagePattern
+}
+
+func newImageNameFilter(include, exclude []string) (imageNameFilter, error) {
+	var f imageNameFilter
+	var err error
+	if f.include, err = newImagePatterns(include); err != nil {
+		return f, err
+	}
+	f.exclude, err = newImagePatterns(exclude)
+	return f, err
+}
+
+func (f imageNameFilter) selects(ref string) bool {
+	for _, p := range f.exclude {
+		if p.matches(ref) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if p.matches(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterIterator yields the selected images, indexed in order of the selected images so excluded images don't
+// count towards the start and count
+func (f imageNameFilter) filterIterator(images func(func(int, string) bool)) func(func(int, string) bool) {
+	return func(yield func(int, string) bool) {
+		idx := 0
+		for _, ref := range images {
+			if !f.selects(ref) {
+				continue
+			}
+			if !yield(idx, ref) {
+				return
+			}
+			idx++
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kzantow-anchore/syft-unknown-analyzer/analyzer"
+)
+
+// unknownRow is a single error reported for a file in an image
+type unknownRow struct {
+	Image     string `json:"image"`
+	Namespace string `json:"namespace,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	File      string `json:"file"`
+	// AccessPath is the path File was accessed by, which is usually the same
+	AccessPath string `json:"accessPath,omitempty"`
+	Layer      string `json:"layer,omitempty"`
+	Size       *int64 `json:"size,omitempty"`
+	MIME       string `json:"mime,omitempty"`
+	Class      string `json:"class,omitempty"`
+	SHA256     string `json:"sha256,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Task       string `json:"task"`
+	Error      string `json:"error"`
+	// RunID identifies the run which scanned the image, at ScannedAt
+	RunID     string    `json:"runId,omitempty"`
+	ScannedAt time.Time `json:"scannedAt"`
+	// Digest is the image pinned to the digest which was scanned, Pinned is false when the digest could not be
+	// resolved and the tag was scanned instead; both are only set when resolving digests
+	Digest string `json:"digest,omitempty"`
+	Pinned *bool  `json:"pinned,omitempty"`
+	// Fingerprint is the normalized error, only set when fingerprinting
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Count is the number of rows collapsed into this one, only set when deduplicating
+	Count int `json:"count,omitempty"`
+	// Hint is guidance for a known error, only set when adding hints
+	Hint string `json:"hint,omitempty"`
+}
+
+// resultWriter is a sink for the unknown rows of a single image
+type resultWriter interface {
+	Write(row unknownRow) error
+	// Flush writes any buffered rows to the underlying io.Writer
+	Flush() error
+	// Close finalizes the output, it does not close the underlying io.Writer
+	Close() error
+}
+
+var resultFormats = []string{"csv", "json", "tsv"}
+
+// optionalColumns are the result columns only included when the feature populating them is enabled
+type optionalColumns struct {
+	fingerprint bool
+	count       bool
+	digest      bool
+	hint        bool
+}
+
+// resultColumns returns the optional columns enabled for the run
+func (c Config) resultColumns() optionalColumns {
+	return optionalColumns{fingerprint: c.Fingerprint, count: c.Dedup, digest: c.ResolveDigests, hint: c.Hints}
+}
+
+// separator returns the field separator of result files for the run, the configured separator is validated
+func (c Config) separator() rune {
+	if c.Separator == "" {
+		return defaultSeparator(c.Format)
+	}
+	r, _ := parseSeparator(c.Separator)
+	return r
+}
+
+// defaultSeparator returns the field separator of the format when none is configured
+func defaultSeparator(format string) rune {
+	if format == "tsv" {
+		return '\t'
+	}
+	return ','
+}
+
+// newResultWriter returns a writer for the format, JSON omits empty optional fields rather than using columns and
+// ignores the separator; headerWritten skips the CSV and TSV header when appending to a file which already has one
+func newResultWriter(format string, w io.Writer, columns optionalColumns, separator rune, headerWritten bool) resultWriter {
+	switch format {
+	case "json":
+		return &jsonResultWriter{w: w}
+	case "tsv":
+		return &tsvResultWriter{w: w, separator: separator, columns: columns, headerWritten: headerWritten}
+	default:
+		cw := csv.NewWriter(w)
+		cw.Comma = separator
+		return &csvResultWriter{w: cw, columns: columns, headerWritten: headerWritten}
+	}
+}
+
+// unsafeFilenameChars matches characters replaced in result filenames, leaving only characters which are valid on
+// all common filesystems
+var unsafeFilenameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// maxSanitizedRefLength keeps result filenames, including the prefix, hash and extension, under the 255 byte limit
+// of most filesystems
+const maxSanitizedRefLength = 200
+
+// sanitizeRef returns a filename-safe form of ref; when characters are replaced or the ref is truncated a short hash
+// of the original is appended, so refs such as a/b and a_b never produce the same filename
+func sanitizeRef(ref string) string {
+	safe := unsafeFilenameChars.ReplaceAllString(ref, "_")
+	// Windows does not allow names ending with a dot
+	if safe == ref && len(safe) <= maxSanitizedRefLength && !strings.HasSuffix(safe, ".") {
+		return safe
+	}
+	if len(safe) > maxSanitizedRefLength {
+		safe = safe[:maxSanitizedRefLength]
+	}
+	sum := sha256.Sum256([]byte(ref))
+	return safe + "-" + hex.EncodeToString(sum[:4])
+}
+
+// resultFilePath returns the path of the result file of ref, ext is the format with ".gz" appended when compressing
+func resultFilePath(resultDir, ref, ext string) string {
+	return filepath.Join(resultDir, fmt.Sprintf("%s%s.%s", resultFilePrefix, sanitizeRef(ref), ext))
+}
+
+// defaultFilenameTemplate names result files as resultFilePath does
+const defaultFilenameTemplate = resultFilePrefix + "{{.Ref}}"
+
+// resultFileName holds the fields available to filename templates, each is safe to use in a file name
+type resultFileName struct {
+	Ref       string
+	Namespace string
+	RunID     string
+	// Date is the day the run started, e.g. 2006-01-02
+	Date string
+}
+
+// resultNamer names result files from a template, relative to the result directory; the template may include
+// directories, and the extension is appended
+type resultNamer struct {
+	dir, ext string
+	tmpl     *template.Template
+	runID    string
+	date     string
+}
+
+func newResultNamer(dir, ext, filenameTemplate, runID string, started time.Time) (*resultNamer, error) {
+	tmpl, err := template.New("filename").Parse(filenameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename template: %w", err)
+	}
+	n := &resultNamer{dir: dir, ext: ext, tmpl: tmpl, runID: runID, date: started.Format(time.DateOnly)}
+	// fail before scanning when the template can't be executed
+	if _, err = n.path("docker.io/library/alpine:latest", "library"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// recognized returns whether the result files are named so they are read back from the result directory, such as
+// by the baseline or merge
+func (n *resultNamer) recognized() bool {
+	p, _ := n.path("docker.io/library/alpine:latest", "library")
+	return strings.HasPrefix(filepath.Base(p), resultFilePrefix)
+}
+
+// path returns the result file path of the image with the scan key
+func (n *resultNamer) path(key, namespace string) (string, error) {
+	var sb strings.Builder
+	err := n.tmpl.Execute(&sb, resultFileName{
+		Ref:       sanitizeRef(key),
+		Namespace: sanitizeRef(namespace),
+		RunID:     n.runID,
+		Date:      n.date,
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid filename template: %w", err)
+	}
+	// an empty field at the start of the template, such as the namespace of a directory, leaves a leading slash
+	name := filepath.Clean(filepath.FromSlash(strings.TrimLeft(sb.String(), "/")))
+	if name == "." || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid filename template: %q is not a file in the result directory", sb.String())
+	}
+	return filepath.Join(n.dir, name+"."+n.ext), nil
+}
+
+// sbomPath returns the path of the SBOM file of the image with the scan key, named as its result file with the SBOM
+// file prefix in place of the result file prefix, so it is never read back as results
+func (n *resultNamer) sbomPath(key, namespace, ext string) (string, error) {
+	p, err := n.path(key, namespace)
+	if err != nil {
+		return "", err
+	}
+	dir, name := filepath.Split(strings.TrimSuffix(p, "."+n.ext))
+	return filepath.Join(dir, sbomFilePrefix+strings.TrimPrefix(name, resultFilePrefix)+"."+ext), nil
+}
+
+// deltaPath returns the path of the delta file of the image with the scan key, named as its result file with the
+// delta file prefix in place of the result file prefix
+func (n *resultNamer) deltaPath(key, namespace string) (string, error) {
+	p, err := n.path(key, namespace)
+	if err != nil {
+		return "", err
+	}
+	dir, name := filepath.Split(strings.TrimSuffix(p, "."+n.ext))
+	return filepath.Join(dir, deltaFilePrefix+strings.TrimPrefix(name, resultFilePrefix)+".csv"), nil
+}
+
+// resultExt returns the result file extension for the run
+func (c Config) resultExt() string {
+	if c.Gzip {
+		return c.Format + ".gz"
+	}
+	return c.Format
+}
+
+// resultFileBufferSize is the size of the buffer between result writers and the file
+const resultFileBufferSize = 64 * 1024
+
+// resultFile writes rows to a buffered file, gzip compressed when the path ends in .gz
+type resultFile struct {
+	resultWriter
+	gz  *gzip.Writer
+	buf *bufio.Writer
+	f   *os.File
+}
+
+// openResultFile opens path with the flags; when appending to an existing CSV or TSV file the header is not repeated
+func openResultFile(path string, flag int, format string, columns optionalColumns, separator rune) (*resultFile, error) {
+	f, err := os.OpenFile(path, flag, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &resultFile{f: f, buf: bufio.NewWriterSize(f, resultFileBufferSize)}
+	var w io.Writer = r.buf
+	if strings.HasSuffix(path, ".gz") {
+		r.gz = gzip.NewWriter(r.buf)
+		w = r.gz
+	}
+	info, err := f.Stat()
+	headerWritten := err == nil && flag&os.O_APPEND != 0 && info.Size() > 0
+	r.resultWriter = newResultWriter(format, w, columns, separator, headerWritten)
+	return r, nil
+}
+
+// Flush writes buffered rows through each layer to the file, as a complete gzip block when compressing
+func (r *resultFile) Flush() error {
+	if err := r.resultWriter.Flush(); err != nil {
+		return err
+	}
+	if r.gz != nil {
+		if err := r.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return r.buf.Flush()
+}
+
+// Close finalizes the rows, then the gzip stream, then flushes the buffer and closes the file; each layer is
+// finalized before the one it writes to, so the file is never truncated
+func (r *resultFile) Close() error {
+	err := r.resultWriter.Close()
+	if r.gz != nil {
+		if gzErr := r.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if flushErr := r.buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := r.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeResultFile writes rows to a new file in the given format, replacing any existing file
+func writeResultFile(path, format string, rows []unknownRow, columns optionalColumns, separator rune) error {
+	w, err := openResultFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, format, columns, separator)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err = w.Write(row); err != nil {
+			_ = w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// unknownRows converts the unknowns of a result to rows of ref, which may differ from the scanned ref when it was
+// pinned to a digest; the layer is only included when requested, since it is only meaningful when cataloging all
+// layers
+func unknownRows(ref string, result analyzer.Result, includeLayer bool) []unknownRow {
+	var rows []unknownRow
+	for _, u := range result.Unknowns {
+		rows = append(rows, newUnknownRow(ref, result.Platform, u, includeLayer))
+	}
+	return rows
+}
+
+func newUnknownRow(ref, platform string, u analyzer.Unknown, includeLayer bool) unknownRow {
+	layer := ""
+	if includeLayer {
+		layer = u.Coordinates.FileSystemID
+	}
+	return unknownRow{
+		Image:      ref,
+		Platform:   platform,
+		File:       u.Coordinates.RealPath,
+		AccessPath: u.AccessPath,
+		Layer:      layer,
+		Size:       u.Size,
+		MIME:       u.MIME,
+		Class:      u.Class,
+		SHA256:     u.SHA256,
+		Kind:       u.Kind,
+		Task:       u.Task,
+		Error:      u.Error,
+	}
+}
+
+// dedupRows collapses rows of identical files with the same task and error into the first row, counting the rows
+// collapsed; files are identified by digest, so rows without a sha256 are never collapsed
+func dedupRows(rows []unknownRow) []unknownRow {
+	type dedupKey struct {
+		task, err, digest string
+	}
+
+	var out []unknownRow
+	seen := map[dedupKey]int{}
+	for _, row := range rows {
+		key := dedupKey{row.Task, row.Error, row.SHA256}
+		if idx, ok := seen[key]; ok && row.SHA256 != "" {
+			out[idx].Count++
+			continue
+		}
+		seen[key] = len(out)
+		row.Count = 1
+		out = append(out, row)
+	}
+	return out
+}
+
+type csvResultWriter struct {
+	w             *csv.Writer
+	columns       optionalColumns
+	headerWritten bool
+}
+
+func (c *csvResultWriter) Write(row unknownRow) error {
+	if !c.headerWritten {
+		c.headerWritten = true
+		if err := c.w.Write(resultHeader(c.columns)); err != nil {
+			return err
+		}
+	}
+	return c.w.Write(resultRecord(row, c.columns))
+}
+
+// resultHeader returns the CSV and TSV column names, the optional columns follow the others
+func resultHeader(columns optionalColumns) []string {
+	header := []string{"IMAGE", "NAMESPACE", "PLATFORM", "FILE", "ACCESS_PATH", "LAYER", "SIZE", "MIME", "CLASS", "SHA256", "KIND", "TASK", "ERROR", "RUN_ID", "SCANNED_AT"}
+	if columns.fingerprint {
+		header = append(header, "FINGERPRINT")
+	}
+	if columns.count {
+		header = append(header, "COUNT")
+	}
+	if columns.digest {
+		header = append(header, "DIGEST", "PINNED")
+	}
+	if columns.hint {
+		header = append(header, "HINT")
+	}
+	return header
+}
+
+// resultRecord returns the fields of a row in the order of resultHeader
+func resultRecord(row unknownRow, columns optionalColumns) []string {
+	size := ""
+	if row.Size != nil {
+		size = strconv.FormatInt(*row.Size, 10)
+	}
+	scannedAt := ""
+	if !row.ScannedAt.IsZero() {
+		scannedAt = row.ScannedAt.UTC().Format(time.RFC3339)
+	}
+	record := []string{row.Image, row.Namespace, row.Platform, row.File, row.AccessPath, row.Layer, size, row.MIME, row.Class, row.SHA256, row.Kind, row.Task, row.Error, row.RunID, scannedAt}
+	if columns.fingerprint {
+		record = append(record, row.Fingerprint)
+	}
+	if columns.count {
+		record = append(record, strconv.Itoa(row.Count))
+	}
+	if columns.digest {
+		pinned := ""
+		if row.Pinned != nil {
+			pinned = strconv.FormatBool(*row.Pinned)
+		}
+		record = append(record, row.Digest, pinned)
+	}
+	if columns.hint {
+		record = append(record, row.Hint)
+	}
+	return record
+}
+
+func (c *csvResultWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Close flushes any buffered rows to the underlying writer
+func (c *csvResultWriter) Close() error {
+	return c.Flush()
+}
+
+// jsonResultWriter writes an indented array of rows, writing each row as it is received
+type jsonResultWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonResultWriter) Write(row unknownRow) error {
+	value, err := json.MarshalIndent(row, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if j.count == 0 {
+		separator = "[\n  "
+	}
+	j.count++
+	if _, err = io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	_, err = j.w.Write(value)
+	return err
+}
+
+// Flush does nothing, rows are not buffered
+func (j *jsonResultWriter) Flush() error {
+	return nil
+}
+
+func (j *jsonResultWriter) Close() error {
+	end := "\n]\n"
+	if j.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// startProfiling starts the CPU profile and execution trace of the run when configured, returning a func which
+// stops them and closes their files
+func startProfiling(cfg Config) (func(), error) {
+	var stops []func()
+	stop := func() {
+		for _, s := range stops {
+			s()
+		}
+	}
+
+	if cfg.CPUProfile != "" {
+		f, err := os.Create(cfg.CPUProfile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create CPU profile: %w", err)
+		}
+		if err = pprof.StartCPUProfile(f); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("unable to start CPU profile: %w", err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			_ = f.Close()
+		})
+	}
+
+	if cfg.Trace != "" {
+		f, err := os.Create(cfg.Trace)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("unable to create trace: %w", err)
+		}
+		if err = trace.Start(f); err != nil {
+			_ = f.Close()
+			stop()
+			return nil, fmt.Errorf("unable to start trace: %w", err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			_ = f.Close()
+		})
+	}
+	return stop, nil
+}
+
+// writeMemProfile writes a heap profile once the scans are done, after a GC so it reflects live memory
+func writeMemProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	runtime.GC()
+	if err = pprof.WriteHeapProfile(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
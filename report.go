@@ -0,0 +1,359 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/anchore/go-sync"
+)
+
+// taskHistogram counts unknown errors by the task which reported them, or by task and fingerprint, across all images
+type taskHistogram struct {
+	sync.Locking
+	byFingerprint bool
+	counts        map[string]int
+}
+
+func newTaskHistogram(byFingerprint bool) *taskHistogram {
+	return &taskHistogram{byFingerprint: byFingerprint, counts: map[string]int{}}
+}
+
+func (h *taskHistogram) add(rows []unknownRow) {
+	defer h.Lock()()
+	for _, row := range rows {
+		key := row.Task
+		if h.byFingerprint {
+			key = strings.TrimPrefix(row.Task+": "+row.Fingerprint, ": ")
+		}
+		h.counts[key]++
+	}
+}
+
+// countEntry is a histogram bucket with its share of the total
+type countEntry struct {
+	Name    string  `json:"name"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// countEntries orders the counts by descending count, returning at most limit entries when limit is > 0
+func countEntries(counts map[string]int, limit int) []countEntry {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+
+	names := maps.Keys(counts)
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+	}
+
+	entries := make([]countEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, countEntry{Name: name, Count: counts[name], Percent: 100 * float64(counts[name]) / float64(total)})
+	}
+	return entries
+}
+
+func printEntries(w io.Writer, title string, entries []countEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintln(w, title)
+	for _, e := range entries {
+		fmt.Fprintf(w, "%v\t%v\t%.1f%%\n", e.Name, e.Count, e.Percent)
+	}
+}
+
+// entries returns the tasks ordered by descending count, at most limit when limit is > 0
+func (h *taskHistogram) entries(limit int) []countEntry {
+	defer h.RLock()()
+	entries := countEntries(h.counts, limit)
+	for i := range entries {
+		if entries[i].Name == "" {
+			entries[i].Name = "(none)"
+		}
+	}
+	return entries
+}
+
+// title describes the histogram, which counts errors by task or by fingerprint
+func (h *taskHistogram) title() string {
+	if h.byFingerprint {
+		return "errors by fingerprint:"
+	}
+	return "errors by task:"
+}
+
+// pathHistogram counts files with unknowns by a bucket derived from their path, such as the directory, across all
+// images
+type pathHistogram struct {
+	sync.Locking
+	title  string
+	bucket func(path string) string
+	counts map[string]int
+}
+
+// newDirHistogram buckets files by their first depth path segments, or by their parent directory when depth is 0
+func newDirHistogram(depth int) *pathHistogram {
+	return &pathHistogram{
+		title:  "files with unknowns by directory:",
+		bucket: func(p string) string { return dirBucket(p, depth) },
+		counts: map[string]int{},
+	}
+}
+
+// dirBucket returns the first depth path segments of the file's directory, or the directory when depth is 0
+func dirBucket(p string, depth int) string {
+	dir := path.Dir(p)
+	if depth <= 0 {
+		return dir
+	}
+	segments := strings.Split(strings.TrimPrefix(dir, "/"), "/")
+	if len(segments) > depth {
+		segments = segments[:depth]
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// newExtensionHistogram buckets files by their lowercase extension, files without one, including dotfiles such as
+// .bashrc, are counted as "(none)"
+func newExtensionHistogram() *pathHistogram {
+	return &pathHistogram{
+		title:  "files with unknowns by extension:",
+		bucket: extensionBucket,
+		counts: map[string]int{},
+	}
+}
+
+// extensionBucket returns the lowercase extension of the file, or "(none)"
+func extensionBucket(p string) string {
+	base := path.Base(p)
+	ext := path.Ext(base)
+	if ext == "" || ext == base {
+		return "(none)"
+	}
+	return strings.ToLower(ext)
+}
+
+// newLayerHistogram counts unknowns by the layer digest of their file, which is only known when cataloging all
+// layers
+func newLayerHistogram() *pathHistogram {
+	return &pathHistogram{
+		title:  "unknowns by layer:",
+		bucket: func(layer string) string { return layer },
+		counts: map[string]int{},
+	}
+}
+
+// unknownLayers returns the layer of each row which has one
+func unknownLayers(rows []unknownRow) []string {
+	var layers []string
+	for _, row := range rows {
+		if row.Layer != "" {
+			layers = append(layers, row.Layer)
+		}
+	}
+	return layers
+}
+
+func (h *pathHistogram) add(paths ...string) {
+	defer h.Lock()()
+	for _, p := range paths {
+		h.counts[h.bucket(p)]++
+	}
+}
+
+// entries returns up to limit buckets ordered by descending count
+func (h *pathHistogram) entries(limit int) []countEntry {
+	defer h.RLock()()
+	return countEntries(h.counts, limit)
+}
+
+// unknownFiles returns the distinct file paths of the rows, a file in more than one layer is included once per layer
+func unknownFiles(rows []unknownRow) []string {
+	seen := map[string]struct{}{}
+	var files []string
+	for _, row := range rows {
+		key := row.Layer + ":" + row.File
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		files = append(files, row.File)
+	}
+	return files
+}
+
+// reportConfig holds the parameters of the report command
+type reportConfig struct {
+	// Inputs are the result directories, or single result files, to report on
+	Inputs []string
+	// JSON writes the report as JSON instead of text
+	JSON bool
+	// Top is the number of entries in each table
+	Top int
+	// DirDepth groups files by their first DirDepth path segments, 0 groups by parent directory
+	DirDepth int
+	// RatioThreshold only lists images with a ratio of files with unknowns over it
+	RatioThreshold float64
+	// Examples is the number of example images and paths listed for each candidate cataloger cluster
+	Examples int
+	// Output receives the report, by default stdout
+	Output io.Writer
+}
+
+func parseReportConfig(args []string) (reportConfig, error) {
+	cfg := reportConfig{Top: 10, Examples: 3, Output: os.Stdout}
+
+	fs := flag.NewFlagSet("analyzer report", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: analyzer report [flags] <result-dir>...\n")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&cfg.JSON, "json", cfg.JSON, "write the report as JSON")
+	fs.IntVar(&cfg.Top, "top", cfg.Top, "number of entries in each table")
+	fs.IntVar(&cfg.DirDepth, "dir-depth", cfg.DirDepth, "group files with unknowns by their first N path segments, 0 for the parent directory")
+	fs.Float64Var(&cfg.RatioThreshold, "ratio-threshold", cfg.RatioThreshold, "only list images with a ratio of files with unknowns to files over this")
+	fs.IntVar(&cfg.Examples, "examples", cfg.Examples, "number of example images and paths listed for each candidate cataloger cluster")
+
+	inputs, err := parseInterspersed(fs, args)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Inputs = inputs
+
+	if len(cfg.Inputs) == 0 {
+		return cfg, fmt.Errorf("at least one result directory is required")
+	}
+	for _, input := range cfg.Inputs {
+		if _, err := os.Stat(input); err != nil {
+			return cfg, fmt.Errorf("not a result directory or file: %s", input)
+		}
+	}
+	if cfg.Top < 1 {
+		return cfg, fmt.Errorf("top must be >= 1, got: %v", cfg.Top)
+	}
+	if cfg.DirDepth < 0 {
+		return cfg, fmt.Errorf("dir-depth must be >= 0, got: %v", cfg.DirDepth)
+	}
+	if cfg.Examples < 0 {
+		return cfg, fmt.Errorf("examples must be >= 0, got: %v", cfg.Examples)
+	}
+	return cfg, nil
+}
+
+// ratioEntry is an image in the worst ratio table
+type ratioEntry struct {
+	Image    string  `json:"image"`
+	Platform string  `json:"platform,omitempty"`
+	Files    int     `json:"files"`
+	Unknowns int     `json:"unknowns"`
+	Ratio    float64 `json:"ratio"`
+}
+
+// runReport is the aggregate report of existing results
+type runReport struct {
+	Images       int          `json:"images"`
+	Rows         int          `json:"rows"`
+	Tasks        []countEntry `json:"tasks"`
+	Fingerprints []countEntry `json:"fingerprints"`
+	Extensions   []countEntry `json:"extensions"`
+	Directories  []countEntry `json:"directories"`
+	Layers       []countEntry `json:"layers"`
+	Ratios       []ratioEntry `json:"ratios"`
+	Candidates   []candidate  `json:"candidates"`
+}
+
+// report aggregates the result files and summaries of earlier runs without scanning, using the same histograms as
+// a scan
+func report(cfg reportConfig) error {
+	tasks := newTaskHistogram(false)
+	fingerprints := newTaskHistogram(true)
+	dirs := newDirHistogram(cfg.DirDepth)
+	extensions := newExtensionHistogram()
+	layers := newLayerHistogram()
+	candidates := newCandidateClusters(cfg.Examples)
+	var summaries []imageSummary
+	r := runReport{}
+
+	for _, input := range cfg.Inputs {
+		byImage, err := readResultDir(input)
+		if err != nil {
+			return err
+		}
+		for key, rows := range sorted(byImage) {
+			for i := range rows {
+				if rows[i].Fingerprint == "" {
+					rows[i].Fingerprint = fingerprint(rows[i].Error)
+				}
+			}
+			tasks.add(rows)
+			fingerprints.add(rows)
+			files := unknownFiles(rows)
+			dirs.add(files...)
+			extensions.add(files...)
+			layers.add(unknownLayers(rows)...)
+			candidates.add(key, rows)
+			r.Images++
+			r.Rows += len(rows)
+		}
+
+		if info, err := os.Stat(input); err == nil && info.IsDir() {
+			s, err := readSummaryCSV(filepath.Join(input, "summary.csv"))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			summaries = append(summaries, s...)
+		}
+	}
+
+	r.Tasks = tasks.entries(cfg.Top)
+	r.Fingerprints = fingerprints.entries(cfg.Top)
+	r.Extensions = extensions.entries(cfg.Top)
+	r.Directories = dirs.entries(cfg.Top)
+	r.Layers = layers.entries(cfg.Top)
+	r.Candidates = candidates.entries(cfg.Top)
+	r.Ratios = []ratioEntry{}
+	for _, s := range highRatio(summaries, cfg.RatioThreshold) {
+		if len(r.Ratios) == cfg.Top {
+			break
+		}
+		r.Ratios = append(r.Ratios, ratioEntry{Image: s.Image, Platform: s.Platform, Files: s.Files, Unknowns: s.Unknowns, Ratio: s.ratio()})
+	}
+
+	if cfg.JSON {
+		enc := json.NewEncoder(cfg.Output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	fmt.Fprintf(cfg.Output, "images with unknowns: %v; rows: %v\n", r.Images, r.Rows)
+	printEntries(cfg.Output, "errors by task:", r.Tasks)
+	printEntries(cfg.Output, "errors by fingerprint:", r.Fingerprints)
+	printEntries(cfg.Output, extensions.title, r.Extensions)
+	printEntries(cfg.Output, dirs.title, r.Directories)
+	printEntries(cfg.Output, layers.title, r.Layers)
+	if len(r.Ratios) > 0 {
+		fmt.Fprintln(cfg.Output, "images with the highest unknown ratio:")
+		for _, e := range r.Ratios {
+			fmt.Fprintf(cfg.Output, "%v\t%.4f\t%v/%v\n", scanKey(e.Image, e.Platform), e.Ratio, e.Unknowns, e.Files)
+		}
+	}
+	printCandidates(cfg.Output, r.Candidates)
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// runDirLayout names date-stamped run directories by the run start time in UTC, using dashes since colons are not
+// valid in Windows file names
+const runDirLayout = "2006-01-02T15-04-05Z"
+
+// runDir returns the date-stamped directory of a run started at started, in parent
+func runDir(parent string, started time.Time) string {
+	return filepath.Join(parent, started.UTC().Format(runDirLayout))
+}
+
+// isRunDir returns whether name is exactly a date-stamped run directory name, so no other directory is ever pruned
+func isRunDir(name string) bool {
+	t, err := time.Parse(runDirLayout, name)
+	return err == nil && t.Format(runDirLayout) == name
+}
+
+// pruneRunDirs removes the oldest run directories in parent beyond keep, returning the removed directories; current
+// is the directory of this run, which is never removed
+func pruneRunDirs(parent string, keep int, current string) ([]string, error) {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return nil, err
+	}
+	var runDirs []string
+	for _, e := range entries {
+		if e.IsDir() && isRunDir(e.Name()) {
+			runDirs = append(runDirs, e.Name())
+		}
+	}
+	// the layout sorts chronologically
+	slices.Sort(runDirs)
+
+	var removed []string
+	for _, name := range runDirs[:max(len(runDirs)-keep, 0)] {
+		dir := filepath.Join(parent, name)
+		if dir == current {
+			continue
+		}
+		if err = os.RemoveAll(dir); err != nil {
+			return removed, fmt.Errorf("unable to remove run directory: %w", err)
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
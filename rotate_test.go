@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestIsRunDir(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "2024-05-01T10-30-00Z", want: true},
+		{name: runDir("", time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)), want: true},
+		{name: "2024-05-01T10:30:00Z"},
+		{name: "2024-05-01T10-30-00"},
+		{name: "2024-5-01T10-30-00Z"},
+		{name: "2024-05-01T10-30-00Z-old"},
+		{name: "2024-13-01T10-30-00Z"},
+		{name: "results"},
+		{name: ""},
+	}
+	for _, test := range tests {
+		if got := isRunDir(test.name); got != test.want {
+			t.Errorf("isRunDir(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPruneRunDirs(t *testing.T) {
+	parent := t.TempDir()
+	for _, name := range []string{
+		"2024-05-01T10-30-00Z",
+		"2024-05-02T10-30-00Z",
+		"2024-05-03T10-30-00Z",
+		"2024-05-04T10-30-00Z",
+		"baseline",
+	} {
+		if err := os.Mkdir(filepath.Join(parent, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a file named like a run directory is not a run directory
+	if err := os.WriteFile(filepath.Join(parent, "2024-04-01T10-30-00Z"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	// the current run is kept even when it is among the oldest
+	current := filepath.Join(parent, "2024-05-01T10-30-00Z")
+
+	removed, err := pruneRunDirs(parent, 2, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(parent, "2024-05-02T10-30-00Z")}
+	if !slices.Equal(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remaining []string
+	for _, e := range entries {
+		remaining = append(remaining, e.Name())
+	}
+	wantRemaining := []string{"2024-04-01T10-30-00Z", "2024-05-01T10-30-00Z", "2024-05-03T10-30-00Z", "2024-05-04T10-30-00Z", "baseline"}
+	if !slices.Equal(remaining, wantRemaining) {
+		t.Errorf("remaining = %v, want %v", remaining, wantRemaining)
+	}
+}
+
+func TestPruneRunDirsKeepsAll(t *testing.T) {
+	parent := t.TempDir()
+	if err := os.Mkdir(filepath.Join(parent, "2024-05-01T10-30-00Z"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := pruneRunDirs(parent, 3, "")
+	if err != nil || len(removed) > 0 {
+		t.Errorf("pruneRunDirs() = %v, %v, want nothing removed", removed, err)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+const runFile = "run.json"
+
+// runMetadata records the provenance of a run, so results from different Syft versions or configurations can be
+// told apart
+type runMetadata struct {
+	RunID            string         `json:"runId"`
+	SyftVersion      string         `json:"syftVersion"`
+	Commit           string         `json:"commit,omitempty"`
+	OS               string         `json:"os"`
+	Arch             string         `json:"arch"`
+	GoVersion        string         `json:"goVersion"`
+	Parallelism      int            `json:"parallelism"`
+	ImageParallelism int            `json:"imageParallelism"`
+	MaxMemory        int            `json:"maxMemory,omitempty"`
+	Sample           int            `json:"sample,omitempty"`
+	Seed             uint64         `json:"seed,omitempty"`
+	Providers        []string       `json:"providers"`
+	Unknowns         UnknownsConfig `json:"unknowns"`
+	StartTime        time.Time      `json:"startTime"`
+	DurationSeconds  float64        `json:"durationSeconds"`
+	// Digests are the digests scanned for each image, when resolving digests
+	Digests map[string]string `json:"digests,omitempty"`
+	// Scanned is the number of images scanned, Clean of which had no unknowns; CleanImages lists them when listing
+	// clean images
+	Scanned     int      `json:"scanned"`
+	Clean       int      `json:"clean"`
+	CleanImages []string `json:"cleanImages,omitempty"`
+	// Packages is the total number of packages found in the scanned images
+	Packages int `json:"packages"`
+	// Missing is the number of images which don't exist, Failed of those which failed to scan
+	Missing int64 `json:"missing"`
+	Failed  int   `json:"failed"`
+	// Aborted is set when the run stopped starting scans after max-failures images failed
+	Aborted bool `json:"aborted,omitempty"`
+	// TimeBoxed is set when the run stopped starting scans at its deadline, leaving Remaining images unscanned
+	TimeBoxed bool  `json:"timeBoxed,omitempty"`
+	Remaining int64 `json:"remaining,omitempty"`
+}
+
+func newRunMetadata(cfg Config, startTime time.Time, duration time.Duration) runMetadata {
+	syftVersion, commit := buildVersions()
+	return runMetadata{
+		RunID:            cfg.RunID,
+		SyftVersion:      syftVersion,
+		Commit:           commit,
+		OS:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+		GoVersion:        runtime.Version(),
+		Parallelism:      cfg.Parallelism,
+		ImageParallelism: cfg.ImageParallelism,
+		MaxMemory:        cfg.MaxMemory,
+		Sample:           cfg.Sample,
+		Seed:             cfg.Seed,
+		Providers:        cfg.sourceProviders(),
+		Unknowns:         cfg.Unknowns,
+		StartTime:        startTime,
+		DurationSeconds:  duration.Seconds(),
+	}
+}
+
+// buildVersions returns the Syft module version, including any replacement, and the VCS revision this tool was
+// built from, when available
+func buildVersions() (syftVersion, commit string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown", ""
+	}
+	syftVersion = "unknown"
+	for _, dep := range info.Deps {
+		if dep.Path != "github.com/anchore/syft" {
+			continue
+		}
+		syftVersion = dep.Version
+		if dep.Replace != nil {
+			syftVersion = dep.Replace.Path + " " + dep.Replace.Version
+		}
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			commit = setting.Value
+		}
+	}
+	return syftVersion, commit
+}
+
+func writeRunMetadata(resultDir string, metadata runMetadata) error {
+	f, err := os.Create(filepath.Join(resultDir, runFile))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(metadata); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3UploadTimeout bounds each upload including the SDK's retries, so a stalled connection doesn't hang a worker
+const s3UploadTimeout = 5 * time.Minute
+
+// s3Uploader uploads result files to an S3 bucket; credentials, the region and an S3-compatible endpoint are found
+// by the AWS SDK's default chain, such as the AWS environment variables, shared config files, SSO or an instance role
+type s3Uploader struct {
+	client *s3.Client
+	bucket string
+	prefix string
+	// root is the directory object keys are relative to
+	root string
+}
+
+// newS3Uploader returns an uploader keying objects by their path relative to root, credentials are retrieved up front
+// so missing credentials are reported before scanning starts
+func newS3Uploader(ctx context.Context, bucket, prefix, root string) (*s3Uploader, error) {
+	// the SDK's client, unlike an http.Client, can add the certificates of AWS_CA_BUNDLE
+	httpClient := awshttp.NewBuildableClient().WithTimeout(s3UploadTimeout)
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if _, err = cfg.Credentials.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("s3 uploads require AWS credentials: %w", err)
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		// S3-compatible servers set with AWS_ENDPOINT_URL_S3 generally only support path-style buckets
+		o.UsePathStyle = o.BaseEndpoint != nil
+	})
+	return &s3Uploader{client: client, bucket: bucket, prefix: prefix, root: root}, nil
+}
+
+// upload puts the file at the prefix, keyed by its path relative to the root so result files of different run
+// directories don't replace each other; a file outside of the root, such as a single result file, is keyed by its name
+func (u *s3Uploader) upload(ctx context.Context, file string) error {
+	key := strings.TrimPrefix(path.Join(u.prefix, u.relativePath(file)), "/")
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	ctx, cancel := context.WithTimeout(ctx, s3UploadTimeout)
+	defer cancel()
+	_, err = u.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    aws.String(key),
+		Body:   f,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to upload s3://%s/%s: %w", u.bucket, key, err)
+	}
+	return nil
+}
+
+// relativePath returns the slash-separated path of file relative to the root, or its name when it is outside of it
+func (u *s3Uploader) relativePath(file string) string {
+	rel, err := filepath.Rel(u.root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(file)
+	}
+	return filepath.ToSlash(rel)
+}
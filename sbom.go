@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/anchore/syft/syft/format/cyclonedxjson"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/sbom"
+)
+
+// sbomFilePrefix starts the names of SBOM files written alongside the result files
+const sbomFilePrefix = "sbom-"
+
+// sbomFormats are the SBOM formats which may be emitted
+var sbomFormats = []string{"spdx-json", "cyclonedx-json"}
+
+// sbomWriter writes the SBOM of each scanned image in a format, next to the image's result file
+type sbomWriter struct {
+	encoder sbom.FormatEncoder
+	ext     string
+}
+
+// newSBOMWriter returns the writer for one of the sbomFormats
+func newSBOMWriter(format string) (*sbomWriter, error) {
+	var (
+		encoder sbom.FormatEncoder
+		ext     string
+		err     error
+	)
+	switch format {
+	case "spdx-json":
+		encoder, err = spdxjson.NewFormatEncoderWithConfig(spdxjson.DefaultEncoderConfig())
+		ext = "spdx.json"
+	case "cyclonedx-json":
+		encoder, err = cyclonedxjson.NewFormatEncoderWithConfig(cyclonedxjson.DefaultEncoderConfig())
+		ext = "cdx.json"
+	default:
+		return nil, fmt.Errorf("unsupported SBOM format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create SBOM encoder: %w", err)
+	}
+	return &sbomWriter{encoder: encoder, ext: ext}, nil
+}
+
+// write encodes the SBOM to path, creating its directory
+func (w *sbomWriter) write(path string, s *sbom.SBOM) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700|os.ModeDir); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if err = w.encoder.Encode(f, *s); err != nil {
+		return err
+	}
+	return f.Close()
+}
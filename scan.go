@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/kzantow-anchore/syft-unknown-analyzer/analyzer"
+
+	"github.com/anchore/go-logger"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+)
+
+// scanner scans individual images, accumulating results which are shared across all images in a run
+type scanner struct {
+	cfg         Config
+	analyzerCfg analyzer.Config
+	sink        Sink
+	completed   *checkpoint
+	agg         *Aggregator
+	metrics     *metrics
+	baseline    *baseline
+	since       *sinceRun
+	memory      *memoryGovernor
+	single      *singleResultFile
+	names       *resultNamer
+	uploader    *s3Uploader
+	sbom        *sbomWriter
+	log         logger.Logger
+	workers     workerIDs
+	// dockerRefs serializes the scans of each ref when removing the images the docker provider pulled
+	dockerRefs refLocks
+}
+
+// scanImage analyzes a single image on a platform and writes its results, any panic from the scan is returned as
+// an error
+func (s *scanner) scanImage(ctx context.Context, idx int, ref, platform string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	key := scanKey(ref, platform)
+	s.cfg.Events(Event{Type: eventStarted, Ref: key, Index: idx})
+	imageStartTime := time.Now()
+
+	cfg := s.analyzerCfg
+	cfg.Platform = platform
+
+	scanRef, pinned := s.resolveDigest(ctx, idx, key, ref)
+	digest := ""
+	if pinned != nil && *pinned {
+		digest = scanRef
+	}
+
+	if s.sbom != nil {
+		cfg.SBOM = func(doc *sbom.SBOM) error {
+			path, err := s.names.sbomPath(key, s.namespace(ref), s.sbom.ext)
+			if err == nil {
+				err = s.sbom.write(path, doc)
+			}
+			if err != nil {
+				return fmt.Errorf("unable to write SBOM: %w", err)
+			}
+			return nil
+		}
+	}
+
+	var stream *rowStream
+	if s.cfg.Stream {
+		w, err := s.sink.Open(ref, platform, s.namespace(ref))
+		if err != nil {
+			return fmt.Errorf("unable to write results: %w", err)
+		}
+		stream = newRowStream(s, ref, platform, w)
+		stream.digest, stream.pinned = digest, pinned
+		cfg.Stream = stream.write
+	}
+
+	// only images pulled for the scan are removed, not those which were already in the Docker daemon
+	pulled := false
+	if s.cleanupDocker() {
+		defer s.dockerRefs.Lock(ref)()
+		pulled = !dockerImageExists(scanRef)
+	}
+	result, err := analyzer.AnalyzeImage(ctx, scanRef, cfg)
+	if result.Provider == "docker" && pulled {
+		// cleanup is best-effort, a failure doesn't fail an image which may already have been scanned
+		defer func() {
+			if err := removeDockerImage(scanRef); err != nil {
+				fmt.Fprintf(s.cfg.Output, "ERROR: %v %s: %v\n", idx, key, err)
+			}
+		}()
+	}
+	if stream != nil {
+		if err != nil {
+			// the rows streamed before the failure are not kept, so the image is scanned again rather than skipped
+			_ = stream.discard()
+		} else if closeErr := stream.close(); closeErr != nil {
+			err = fmt.Errorf("unable to write results: %w", closeErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if stream != nil {
+		path, err := s.resultFile(key, s.namespace(ref), len(stream.counted))
+		if err != nil {
+			return err
+		}
+		return s.finish(ctx, idx, key, path, stream.summary(result, time.Since(imageStartTime)), result.HashTime, stream.counted, stream.newFiles)
+	}
+
+	rows := unknownRows(ref, result, s.includeLayer())
+	namespace := s.namespace(ref)
+	for i := range rows {
+		rows[i].Namespace = namespace
+		rows[i].RunID, rows[i].ScannedAt = s.cfg.RunID, imageStartTime
+		if pinned != nil {
+			rows[i].Digest, rows[i].Pinned = digest, pinned
+		}
+		if s.cfg.Fingerprint {
+			rows[i].Fingerprint = fingerprint(rows[i].Error)
+		}
+		if s.cfg.Hints {
+			rows[i].Hint = hint(rows[i].Error)
+		}
+	}
+	var change sinceChange
+	if s.since != nil {
+		change = s.since.compare(key, rows)
+	}
+	scanned := rows
+	if s.baseline != nil {
+		// only report unknowns which are new since the baseline run
+		rows = s.baseline.newRows(key, rows)
+	}
+
+	if err = writeRows(s.sink, ref, platform, namespace, rows); err != nil {
+		return fmt.Errorf("unable to write results: %w", err)
+	}
+	if s.since != nil {
+		path, err := s.names.deltaPath(key, namespace)
+		if err == nil {
+			err = change.write(path)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to write delta: %w", err)
+		}
+	}
+	path, err := s.resultFile(key, namespace, len(rows))
+	if err != nil {
+		return err
+	}
+	if err = s.finish(ctx, idx, key, path, newImageSummary(ref, result, rows, time.Since(imageStartTime)), result.HashTime, rows, unknownFiles(rows)); err != nil {
+		return err
+	}
+	if s.baseline != nil {
+		s.baseline.record(key, scanned, len(rows))
+	}
+	if s.since != nil {
+		s.since.record(change)
+	}
+	return nil
+}
+
+// writeRows writes the rows of an image to the sink, opening a writer even when there are no rows so earlier results
+// of the image are replaced
+func writeRows(sink Sink, ref, platform, namespace string, rows []unknownRow) error {
+	w, err := sink.Open(ref, platform, namespace)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err = w.WriteRow(row); err != nil {
+			_ = w.Discard()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// resultFile returns the path of the file with the results of an image, empty when it had no rows
+func (s *scanner) resultFile(key, namespace string, rows int) (string, error) {
+	switch {
+	case rows == 0:
+		return "", nil
+	case s.single != nil:
+		return s.single.path, nil
+	}
+	return s.names.path(key, namespace)
+}
+
+// errResultExists skips an image which already has a result file, when skipping existing results
+var errResultExists = errors.New("result file exists")
+
+// hasResult returns true when the image already has a non-empty result file, named as this run would name it
+func (s *scanner) hasResult(key, ref string) bool {
+	path, err := s.names.path(key, s.namespace(ref))
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Size() > 0
+}
+
+// resolveDigest returns the ref to scan, pinned to its digest when resolving digests, and whether it was pinned;
+// when the digest can't be resolved the tag is scanned, and pinned is nil when not resolving digests
+func (s *scanner) resolveDigest(ctx context.Context, idx int, key, ref string) (string, *bool) {
+	if !s.cfg.ResolveDigests {
+		return ref, nil
+	}
+	pinned, err := analyzer.ResolveDigest(ctx, ref, s.analyzerCfg.RegistryOptions)
+	if err != nil {
+		fmt.Fprintf(s.cfg.Output, "WARNING: %v %s: scanning the tag: %v\n", idx, key, err)
+		return ref, new(bool)
+	}
+
+	s.agg.RecordDigest(key, pinned)
+	ok := true
+	return pinned, &ok
+}
+
+// namespace returns the registry namespace of an image ref, empty for other source types
+func (s *scanner) namespace(ref string) string {
+	if s.cfg.SourceType != "image" {
+		return ""
+	}
+	return refNamespace(ref)
+}
+
+// includeLayer returns true when result rows include the layer, which is only meaningful when cataloging all layers
+func (s *scanner) includeLayer() bool {
+	return s.cfg.scope() == source.AllLayersScope
+}
+
+// finish uploads the result file and records a scanned image as complete, with the rows and distinct files of its
+// unknowns; resultFilePath is empty when no result file was written
+func (s *scanner) finish(ctx context.Context, idx int, key, resultFilePath string, summary imageSummary, hashTime time.Duration, rows []unknownRow, files []string) error {
+	// the single result file is uploaded once the run is complete
+	upload := s.uploader != nil && resultFilePath != "" && s.single == nil
+	if upload {
+		if err := s.uploader.upload(ctx, resultFilePath); err != nil {
+			return fmt.Errorf("unable to upload results: %w", err)
+		}
+	}
+	if err := s.completed.markComplete(key, resultFilePath); err != nil {
+		return fmt.Errorf("unable to update checkpoint: %w", err)
+	}
+	if upload && s.cfg.S3DeleteLocal {
+		if err := os.Remove(resultFilePath); err != nil {
+			fmt.Fprintf(s.cfg.Output, "ERROR: unable to remove uploaded result file: %v\n", err)
+		}
+	}
+
+	s.agg.RecordImage(key, summary, hashTime)
+	s.agg.RecordUnknown(rows, files)
+	s.metrics.imageScanned(summary.Files, len(rows), summary.Duration)
+	s.cfg.Events(Event{Type: eventCompleted, Ref: key, Index: idx, Duration: summary.Duration, FileCount: summary.Files,
+		UnknownCount: len(rows)})
+	return nil
+}
+
+// cleanupDocker returns true when images the docker provider pulled are removed from the Docker daemon after they
+// are scanned
+func (s *scanner) cleanupDocker() bool {
+	return s.cfg.SourceType == "image" && !s.cfg.NoCleanup && slices.Contains(s.cfg.Providers, "docker")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+// singleResultFile is one result file shared by all workers, the rows of each image are written together while
+// holding the lock, so rows of different images never interleave
+type singleResultFile struct {
+	lock sync.Mutex
+	path string
+	w    *resultFile
+	// dedup collapses the duplicate rows of each image before they are written
+	dedup bool
+}
+
+// openSingleResultFile creates the file, or with appendRows appends CSV or TSV rows to it, only writing the header
+// when the file is new or empty
+func openSingleResultFile(path, format string, columns optionalColumns, separator rune, appendRows bool) (*singleResultFile, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendRows {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	w, err := openResultFile(path, flags, format, columns, separator)
+	if err != nil {
+		return nil, err
+	}
+	return &singleResultFile{path: path, w: w}, nil
+}
+
+// write appends the rows of an image, flushing them to the file before returning so a completed image is never
+// recorded in the checkpoint with rows still buffered
+func (s *singleResultFile) write(rows []unknownRow) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, row := range rows {
+		if err := s.w.Write(row); err != nil {
+			return err
+		}
+	}
+	return s.w.Flush()
+}
+
+func (s *singleResultFile) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.w.Close()
+}
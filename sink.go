@@ -0,0 +1,292 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// dbBatchSize is the number of rows inserted into the database in each transaction
+const dbBatchSize = 1000
+
+// Sink stores the result rows of scanned images, one RowWriter is opened for each image
+type Sink interface {
+	// Open returns a writer for the rows of the image scanned on the platform, which is in the namespace, replacing
+	// any earlier results of the scan
+	Open(ref, platform, namespace string) (RowWriter, error)
+	// Close is called once all images are written
+	Close() error
+}
+
+// RowWriter writes the rows of one image, rows may be buffered until it is closed
+type RowWriter interface {
+	WriteRow(row unknownRow) error
+	// Close stores the rows written once every row of the image is written
+	Close() error
+	// Discard drops the rows written when the image couldn't be scanned, leaving its earlier results
+	Discard() error
+}
+
+// newSink returns the sink for the configured result format: a file per image or the single result file, and the
+// database when there is one
+func newSink(cfg Config, names *resultNamer, single *singleResultFile, db *resultDB) Sink {
+	var sinks multiSink
+	if single != nil {
+		sinks = append(sinks, single)
+	} else {
+		sinks = append(sinks, &fileSink{
+			names:     names,
+			format:    cfg.Format,
+			separator: cfg.separator(),
+			columns:   cfg.resultColumns(),
+			dedup:     cfg.Dedup,
+		})
+	}
+	if db != nil {
+		sinks = append(sinks, dbSink{db: db, runID: cfg.RunID})
+	}
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return sinks
+}
+
+// fileSink writes a result file per image in the format, the file and its directory are only created once there is
+// a row to write; rows are written to a temporary file in the same directory which is renamed to the result file when
+// closed, so a result file of a scan which failed or was interrupted is never left to be skipped as existing
+type fileSink struct {
+	names     *resultNamer
+	format    string
+	separator rune
+	columns   optionalColumns
+	dedup     bool
+}
+
+func (s *fileSink) Open(ref, platform, namespace string) (RowWriter, error) {
+	path, err := s.names.path(scanKey(ref, platform), namespace)
+	if err != nil {
+		return nil, err
+	}
+	tmp := filepath.Join(filepath.Dir(path), partialFilePrefix+filepath.Base(path))
+	return &fileRowWriter{sink: s, path: path, tmp: tmp}, nil
+}
+
+func (s *fileSink) Close() error {
+	return nil
+}
+
+// partialFilePrefix names the temporary file a result file is written to, hidden and without the result file prefix
+// so it isn't read as a result file
+const partialFilePrefix = ".partial-"
+
+type fileRowWriter struct {
+	sink *fileSink
+	path string
+	tmp  string
+	w    *resultFile
+	// rows are buffered to be deduplicated when closed
+	rows []unknownRow
+}
+
+func (f *fileRowWriter) WriteRow(row unknownRow) error {
+	if f.sink.dedup {
+		f.rows = append(f.rows, row)
+		return nil
+	}
+	if f.w == nil {
+		if err := os.MkdirAll(filepath.Dir(f.path), 0700|os.ModeDir); err != nil {
+			return err
+		}
+		w, err := openResultFile(f.tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.sink.format, f.sink.columns, f.sink.separator)
+		if err != nil {
+			return err
+		}
+		f.w = w
+	}
+	return f.w.Write(row)
+}
+
+// Close renames the written file to the result file, removing the earlier result file of an image without any rows
+func (f *fileRowWriter) Close() error {
+	var err error
+	switch {
+	case len(f.rows) > 0:
+		if err = os.MkdirAll(filepath.Dir(f.path), 0700|os.ModeDir); err != nil {
+			return err
+		}
+		err = writeResultFile(f.tmp, f.sink.format, dedupRows(f.rows), f.sink.columns, f.sink.separator)
+	case f.w != nil:
+		err = f.w.Close()
+	default:
+		if err = os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err != nil {
+		_ = os.Remove(f.tmp)
+		return err
+	}
+	return os.Rename(f.tmp, f.path)
+}
+
+func (f *fileRowWriter) Discard() error {
+	f.rows = nil
+	if f.w == nil {
+		return nil
+	}
+	_ = f.w.Close()
+	return os.Remove(f.tmp)
+}
+
+// Open returns a writer buffering the rows of an image, which are written together when it is closed
+func (s *singleResultFile) Open(_, _, _ string) (RowWriter, error) {
+	return &singleRowWriter{file: s}, nil
+}
+
+type singleRowWriter struct {
+	file *singleResultFile
+	rows []unknownRow
+}
+
+func (w *singleRowWriter) WriteRow(row unknownRow) error {
+	w.rows = append(w.rows, row)
+	return nil
+}
+
+func (w *singleRowWriter) Close() error {
+	if len(w.rows) == 0 {
+		return nil
+	}
+	if w.file.dedup {
+		w.rows = dedupRows(w.rows)
+	}
+	return w.file.write(w.rows)
+}
+
+func (w *singleRowWriter) Discard() error {
+	w.rows = nil
+	return nil
+}
+
+// dbSink inserts the rows of each image into the database in batches, rows are never deduplicated in the database;
+// the rows an image already has in the run, such as from a failed attempt before it was retried, are replaced
+type dbSink struct {
+	db    *resultDB
+	runID string
+}
+
+func (s dbSink) Open(ref, platform, _ string) (RowWriter, error) {
+	return &dbRowWriter{db: s.db, runID: s.runID, ref: ref, platform: platform}, nil
+}
+
+// Close leaves the database open, it is closed by its owner
+func (s dbSink) Close() error {
+	return nil
+}
+
+type dbRowWriter struct {
+	db       *resultDB
+	runID    string
+	ref      string
+	platform string
+	batch    []unknownRow
+	// replaced is set once the image's earlier rows are deleted, with the first batch
+	replaced bool
+}
+
+func (w *dbRowWriter) WriteRow(row unknownRow) error {
+	w.batch = append(w.batch, row)
+	if len(w.batch) >= dbBatchSize {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *dbRowWriter) flush() error {
+	if len(w.batch) == 0 && w.replaced {
+		return nil
+	}
+	var err error
+	if w.replaced {
+		err = w.db.insert(w.batch)
+	} else {
+		err = w.db.replace(w.runID, w.ref, w.platform, w.batch)
+		w.replaced = err == nil
+	}
+	w.batch = w.batch[:0]
+	return err
+}
+
+// Close inserts the last batch, deleting the earlier rows of an image without any rows now
+func (w *dbRowWriter) Close() error {
+	return w.flush()
+}
+
+// Discard deletes the rows already inserted, so an image which couldn't be scanned has no rows as when not streaming
+func (w *dbRowWriter) Discard() error {
+	w.batch = w.batch[:0]
+	if !w.replaced {
+		return nil
+	}
+	return w.db.replace(w.runID, w.ref, w.platform, nil)
+}
+
+// multiSink writes every row to each of its sinks
+type multiSink []Sink
+
+func (m multiSink) Open(ref, platform, namespace string) (RowWriter, error) {
+	var writers multiRowWriter
+	for _, s := range m {
+		w, err := s.Open(ref, platform, namespace)
+		if err != nil {
+			_ = writers.Discard()
+			return nil, err
+		}
+		writers = append(writers, w)
+	}
+	return writers, nil
+}
+
+// Close closes every sink, returning the first error
+func (m multiSink) Close() error {
+	var err error
+	for _, s := range m {
+		if closeErr := s.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+type multiRowWriter []RowWriter
+
+func (m multiRowWriter) WriteRow(row unknownRow) error {
+	for _, w := range m {
+		if err := w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every writer, returning the first error
+func (m multiRowWriter) Close() error {
+	var err error
+	for _, w := range m {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Discard discards every writer, returning the first error
+func (m multiRowWriter) Discard() error {
+	var err error
+	for _, w := range m {
+		if discardErr := w.Discard(); err == nil {
+			err = discardErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSinkKeepsOnlyCompleteResults(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ResultDir = t.TempDir()
+	names, err := newResultNamer(cfg.ResultDir, cfg.resultExt(), cfg.FilenameTemplate, "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &fileSink{names: names, format: cfg.Format, separator: cfg.separator(), columns: cfg.resultColumns()}
+
+	const ref = "example/app:1"
+	row := unknownRow{Image: ref, File: "/app.jar", Task: "java-archive-cataloger", Error: "not a valid zip file"}
+	scan := func(complete bool, rows ...unknownRow) {
+		t.Helper()
+		w, err := sink.Open(ref, "", "example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			if err = w.WriteRow(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if complete {
+			err = w.Close()
+		} else {
+			err = w.Discard()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// files returns the names of every file in the result directory
+	files := func() []string {
+		t.Helper()
+		var out []string
+		err := filepath.WalkDir(cfg.ResultDir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				out = append(out, d.Name())
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	scan(false, row)
+	if got := files(); len(got) > 0 {
+		t.Fatalf("files = %v, want nothing from a failed scan", got)
+	}
+
+	scan(true, row)
+	got := files()
+	if len(got) != 1 || !strings.HasPrefix(got[0], resultFilePrefix) {
+		t.Fatalf("files = %v, want only the result file", got)
+	}
+	key := scanKey(ref, "")
+	results, err := readResultDir(cfg.ResultDir)
+	if err != nil || len(results[key]) != 1 {
+		t.Fatalf("results = %v, %v, want the row", results, err)
+	}
+
+	// a failed scan leaves the earlier complete result
+	scan(false, row, row)
+	if results, err = readResultDir(cfg.ResultDir); err != nil || len(results[key]) != 1 {
+		t.Errorf("results = %v, %v, want the earlier row", results, err)
+	}
+	if got := files(); len(got) != 1 {
+		t.Errorf("files = %v, want the partial file removed", got)
+	}
+
+	// a scan without rows removes the earlier result
+	scan(true)
+	if got := files(); len(got) > 0 {
+		t.Errorf("files = %v, want the earlier result removed", got)
+	}
+}
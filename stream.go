@@ -0,0 +1,99 @@
+package main
+
+import (
+	"time"
+
+	"github.com/kzantow-anchore/syft-unknown-analyzer/analyzer"
+)
+
+// rowStream writes the rows of an image to the sink as unknowns are found, so memory use only grows by the few
+// fields of each unknown which are aggregated rather than with whole rows
+type rowStream struct {
+	ref, platform string
+	namespace     string
+	digest        string
+	pinned        *bool
+	runID         string
+	scannedAt     time.Time
+	includeLayer  bool
+	fingerprint   bool
+	hints         bool
+
+	w RowWriter
+
+	files     map[string]struct{}
+	taskNames map[string]struct{}
+	// counted are the parts of the rows written which are aggregated, and newFiles the distinct files; they are
+	// only recorded once the image is finished, so a scan which fails doesn't count its unknowns when it is retried
+	counted  []unknownRow
+	newFiles []string
+}
+
+func newRowStream(s *scanner, ref, platform string, w RowWriter) *rowStream {
+	return &rowStream{
+		ref:          ref,
+		platform:     platform,
+		namespace:    s.namespace(ref),
+		runID:        s.cfg.RunID,
+		scannedAt:    time.Now(),
+		includeLayer: s.includeLayer(),
+		fingerprint:  s.cfg.Fingerprint,
+		hints:        s.cfg.Hints,
+		w:            w,
+		files:        map[string]struct{}{},
+		taskNames:    map[string]struct{}{},
+	}
+}
+
+func (r *rowStream) write(u analyzer.Unknown) error {
+	row := newUnknownRow(r.ref, r.platform, u, r.includeLayer)
+	row.Namespace = r.namespace
+	row.RunID, row.ScannedAt = r.runID, r.scannedAt
+	if r.pinned != nil {
+		row.Digest, row.Pinned = r.digest, r.pinned
+	}
+	if r.fingerprint {
+		row.Fingerprint = fingerprint(row.Error)
+	}
+	if r.hints {
+		row.Hint = hint(row.Error)
+	}
+
+	if err := r.w.WriteRow(row); err != nil {
+		return err
+	}
+
+	r.counted = append(r.counted, unknownRow{Task: row.Task, Fingerprint: row.Fingerprint, Layer: row.Layer})
+	fileKey := row.Layer + ":" + row.File
+	if _, ok := r.files[fileKey]; !ok {
+		r.files[fileKey] = struct{}{}
+		r.newFiles = append(r.newFiles, row.File)
+	}
+	r.taskNames[row.Task] = struct{}{}
+	return nil
+}
+
+// close finishes writing the rows of the image
+func (r *rowStream) close() error {
+	return r.w.Close()
+}
+
+func (r *rowStream) discard() error {
+	return r.w.Discard()
+}
+
+func (r *rowStream) summary(result analyzer.Result, duration time.Duration) imageSummary {
+	return imageSummary{
+		Image:    r.ref,
+		Platform: r.platform,
+		Files:    result.FileCount,
+		Packages: result.PackageCount,
+		Unknowns: len(r.files),
+		Tasks:    len(r.taskNames),
+		Duration: duration,
+		Pull:     result.PullTime,
+		Catalog:  result.CatalogTime,
+
+		PeakMemory: result.PeakMemory,
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kzantow-anchore/syft-unknown-analyzer/analyzer"
+)
+
+// imageSummary holds the aggregate results of scanning a single image
+type imageSummary struct {
+	Image    string
+	Platform string
+	Files    int
+	Packages int
+	Unknowns int
+	Tasks    int
+	Duration time.Duration
+	// Pull and Catalog are the parts of the duration spent getting the source and creating the SBOM
+	Pull    time.Duration
+	Catalog time.Duration
+	// PeakMemory is the approximate peak increase in heap bytes while cataloging, 0 when not profiling memory
+	PeakMemory uint64
+}
+
+// ratio returns the fraction of files with unknowns
+func (s imageSummary) ratio() float64 {
+	if s.Files == 0 {
+		return 0
+	}
+	return float64(s.Unknowns) / float64(s.Files)
+}
+
+// packageRatio returns the fraction of packages among the packages found and files with unknowns, a quick signal of
+// how well the image is cataloged
+func (s imageSummary) packageRatio() float64 {
+	if s.Packages+s.Unknowns == 0 {
+		return 0
+	}
+	return float64(s.Packages) / float64(s.Packages+s.Unknowns)
+}
+
+// newImageSummary summarizes the result and rows of an image, ref may differ from the scanned ref when it was pinned
+// to a digest
+func newImageSummary(ref string, result analyzer.Result, rows []unknownRow, duration time.Duration) imageSummary {
+	tasks := map[string]struct{}{}
+	for _, row := range rows {
+		tasks[row.Task] = struct{}{}
+	}
+	return imageSummary{
+		Image:    ref,
+		Platform: result.Platform,
+		Files:    result.FileCount,
+		Packages: result.PackageCount,
+		Unknowns: result.UnknownFiles(),
+		Tasks:    len(tasks),
+		Duration: duration,
+		Pull:     result.PullTime,
+		Catalog:  result.CatalogTime,
+
+		PeakMemory: result.PeakMemory,
+	}
+}
+
+// writeSummary writes summary.csv, and optionally summary.json, to the result directory ordered by image
+func writeSummary(resultDir string, summaries []imageSummary, writeJSON bool) error {
+	summaries = slices.Clone(summaries)
+	slices.SortFunc(summaries, func(a, b imageSummary) int {
+		return cmp.Or(strings.Compare(a.Image, b.Image), strings.Compare(a.Platform, b.Platform))
+	})
+
+	if err := writeSummaryCSV(filepath.Join(resultDir, "summary.csv"), summaries); err != nil {
+		return fmt.Errorf("unable to write summary: %w", err)
+	}
+	if writeJSON {
+		if err := writeSummaryJSON(filepath.Join(resultDir, "summary.json"), summaries); err != nil {
+			return fmt.Errorf("unable to write summary: %w", err)
+		}
+	}
+	return nil
+}
+
+// highRatio returns the images with a ratio of files with unknowns over threshold, highest first; these usually
+// contain an ecosystem no cataloger supports
+func highRatio(summaries []imageSummary, threshold float64) []imageSummary {
+	var high []imageSummary
+	for _, s := range summaries {
+		if s.ratio() > threshold {
+			high = append(high, s)
+		}
+	}
+	slices.SortFunc(high, func(a, b imageSummary) int {
+		return cmp.Or(cmp.Compare(b.ratio(), a.ratio()), strings.Compare(a.Image, b.Image),
+			strings.Compare(a.Platform, b.Platform))
+	})
+	return high
+}
+
+func printHighRatio(w io.Writer, summaries []imageSummary, threshold float64) {
+	high := highRatio(summaries, threshold)
+	if len(high) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "images with an unknown ratio over %v:\n", threshold)
+	for _, s := range high {
+		fmt.Fprintf(w, "%v\t%.4f\t%v/%v\n", scanKey(s.Image, s.Platform), s.ratio(), s.Unknowns, s.Files)
+	}
+}
+
+// cleanImages returns the scan keys of the images without unknowns, sorted
+func cleanImages(summaries []imageSummary) []string {
+	var out []string
+	for _, s := range summaries {
+		if s.Unknowns == 0 {
+			out = append(out, scanKey(s.Image, s.Platform))
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// printCleanImages prints the number of images scanned without unknowns, and optionally lists them
+func printCleanImages(w io.Writer, summaries []imageSummary, list bool) {
+	clean := cleanImages(summaries)
+	fmt.Fprintf(w, "images without unknowns: %v of %v scanned\n", len(clean), len(summaries))
+	if !list {
+		return
+	}
+	for _, key := range clean {
+		fmt.Fprintf(w, "\t%v\n", key)
+	}
+}
+
+// peakMemoryImages is the number of images printed with the highest peak memory
+const peakMemoryImages = 10
+
+// printPeakMemory prints the images with the highest peak memory while cataloging, when memory was profiled
+func printPeakMemory(w io.Writer, summaries []imageSummary, limit int) {
+	summaries = slices.DeleteFunc(slices.Clone(summaries), func(s imageSummary) bool {
+		return s.PeakMemory == 0
+	})
+	if len(summaries) == 0 {
+		return
+	}
+	slices.SortFunc(summaries, func(a, b imageSummary) int {
+		return cmp.Or(cmp.Compare(b.PeakMemory, a.PeakMemory), strings.Compare(a.Image, b.Image),
+			strings.Compare(a.Platform, b.Platform))
+	})
+	fmt.Fprintf(w, "images with the highest peak memory while cataloging:\n")
+	for _, s := range summaries[:min(limit, len(summaries))] {
+		fmt.Fprintf(w, "%v\t%v MiB\n", scanKey(s.Image, s.Platform), s.PeakMemory>>20)
+	}
+}
+
+// printStageTimes prints the total and mean time images spent being pulled and cataloged, telling a slow registry
+// from slow cataloging
+func printStageTimes(w io.Writer, summaries []imageSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+	var pull, catalog time.Duration
+	for _, s := range summaries {
+		pull += s.Pull
+		catalog += s.Catalog
+	}
+	n := time.Duration(len(summaries))
+	fmt.Fprintf(w, "time pulling: %v, mean %v; cataloging: %v, mean %v\n", pull, pull/n, catalog, catalog/n)
+}
+
+// peakMemory formats the peak memory of an image, empty when memory was not profiled
+func peakMemory(bytes uint64) string {
+	if bytes == 0 {
+		return ""
+	}
+	return strconv.FormatUint(bytes, 10)
+}
+
+func writeSummaryCSV(path string, summaries []imageSummary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"IMAGE", "PLATFORM", "FILES", "PACKAGES", "UNKNOWNS", "RATIO", "PACKAGE_RATIO", "TASKS", "DURATION_SECONDS", "PULL_SECONDS", "CATALOG_SECONDS", "PEAK_MEMORY_BYTES"})
+	for _, s := range summaries {
+		_ = w.Write([]string{
+			s.Image,
+			s.Platform,
+			strconv.Itoa(s.Files),
+			strconv.Itoa(s.Packages),
+			strconv.Itoa(s.Unknowns),
+			strconv.FormatFloat(s.ratio(), 'f', 4, 64),
+			strconv.FormatFloat(s.packageRatio(), 'f', 4, 64),
+			strconv.Itoa(s.Tasks),
+			strconv.FormatFloat(s.Duration.Seconds(), 'f', 3, 64),
+			strconv.FormatFloat(s.Pull.Seconds(), 'f', 3, 64),
+			strconv.FormatFloat(s.Catalog.Seconds(), 'f', 3, 64),
+			peakMemory(s.PeakMemory),
+		})
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// readSummaryCSV reads a summary.csv, columns are matched by name so summaries written by older versions are read
+func readSummaryCSV(path string) ([]imageSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	var out []imageSummary
+	for _, record := range records[1:] {
+		field := func(name string) string {
+			idx := slices.Index(header, name)
+			if idx < 0 || idx >= len(record) {
+				return ""
+			}
+			return record[idx]
+		}
+		number := func(name string) int {
+			n, _ := strconv.Atoi(field(name))
+			return n
+		}
+		duration := func(name string) time.Duration {
+			seconds, _ := strconv.ParseFloat(field(name), 64)
+			return time.Duration(seconds * float64(time.Second))
+		}
+		peak, _ := strconv.ParseUint(field("PEAK_MEMORY_BYTES"), 10, 64)
+		out = append(out, imageSummary{
+			Image:    field("IMAGE"),
+			Platform: field("PLATFORM"),
+			Files:    number("FILES"),
+			Packages: number("PACKAGES"),
+			Unknowns: number("UNKNOWNS"),
+			Tasks:    number("TASKS"),
+			Duration: duration("DURATION_SECONDS"),
+			Pull:     duration("PULL_SECONDS"),
+			Catalog:  duration("CATALOG_SECONDS"),
+
+			PeakMemory: peak,
+		})
+	}
+	return out, nil
+}
+
+func writeSummaryJSON(path string, summaries []imageSummary) error {
+	type summaryJSON struct {
+		Image           string  `json:"image"`
+		Platform        string  `json:"platform,omitempty"`
+		Files           int     `json:"files"`
+		Packages        int     `json:"packages"`
+		Unknowns        int     `json:"unknowns"`
+		Ratio           float64 `json:"ratio"`
+		PackageRatio    float64 `json:"packageRatio"`
+		Tasks           int     `json:"tasks"`
+		DurationSeconds float64 `json:"durationSeconds"`
+		PullSeconds     float64 `json:"pullSeconds"`
+		CatalogSeconds  float64 `json:"catalogSeconds"`
+		PeakMemoryBytes uint64  `json:"peakMemoryBytes,omitempty"`
+	}
+
+	out := make([]summaryJSON, 0, len(summaries))
+	for _, s := range summaries {
+		out = append(out, summaryJSON{
+			Image:           s.Image,
+			Platform:        s.Platform,
+			Files:           s.Files,
+			Packages:        s.Packages,
+			Unknowns:        s.Unknowns,
+			Ratio:           s.ratio(),
+			PackageRatio:    s.packageRatio(),
+			Tasks:           s.Tasks,
+			DurationSeconds: s.Duration.Seconds(),
+			PullSeconds:     s.Pull.Seconds(),
+			CatalogSeconds:  s.Catalog.Seconds(),
+			PeakMemoryBytes: s.PeakMemory,
+		})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(out); err != nil {
+		return err
+	}
+	return f.Close()
+}
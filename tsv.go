@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tsvResultWriter writes one line per row with unquoted fields, so results can be read with cut or awk; backslashes,
+// tabs, newlines, carriage returns and the separator in fields are escaped with a backslash
+type tsvResultWriter struct {
+	w             io.Writer
+	separator     rune
+	columns       optionalColumns
+	headerWritten bool
+}
+
+func (t *tsvResultWriter) Write(row unknownRow) error {
+	if !t.headerWritten {
+		t.headerWritten = true
+		if err := t.writeRecord(resultHeader(t.columns)); err != nil {
+			return err
+		}
+	}
+	return t.writeRecord(resultRecord(row, t.columns))
+}
+
+func (t *tsvResultWriter) writeRecord(record []string) error {
+	var b strings.Builder
+	for i, field := range record {
+		if i > 0 {
+			b.WriteRune(t.separator)
+		}
+		escapeTSV(&b, field, t.separator)
+	}
+	b.WriteByte('\n')
+	_, err := io.WriteString(t.w, b.String())
+	return err
+}
+
+// Flush does nothing, rows are not buffered
+func (t *tsvResultWriter) Flush() error {
+	return nil
+}
+
+// Close does nothing, rows are not buffered
+func (t *tsvResultWriter) Close() error {
+	return nil
+}
+
+func escapeTSV(b *strings.Builder, field string, separator rune) {
+	for _, r := range field {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case separator:
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// tsvReader reads the records written by tsvResultWriter, unescaping the fields
+type tsvReader struct {
+	r         *bufio.Reader
+	separator rune
+}
+
+func (t *tsvReader) Read() ([]string, error) {
+	line, err := t.r.ReadString('\n')
+	if errors.Is(err, io.EOF) && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+	var record []string
+	var field strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+			switch r {
+			case 't':
+				r = '\t'
+			case 'n':
+				r = '\n'
+			case 'r':
+				r = '\r'
+			}
+			field.WriteRune(r)
+		case r == '\\':
+			escaped = true
+		case r == t.separator:
+			record = append(record, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	return append(record, field.String()), nil
+}
+
+// headerSeparator returns the separator of a CSV or TSV result file from its header, which starts with the IMAGE
+// column; def is returned when the file is empty
+func headerSeparator(r *bufio.Reader, def rune) rune {
+	const first = "IMAGE"
+	peek, _ := r.Peek(len(first) + utf8.UTFMax)
+	if !strings.HasPrefix(string(peek), first) {
+		return def
+	}
+	sep, _ := utf8.DecodeRune(peek[len(first):])
+	if sep == utf8.RuneError {
+		return def
+	}
+	return sep
+}
+
+// parseSeparator returns the single character of a separator, accepting \t for a tab since it is awkward to type;
+// letters and digits are not allowed since they are used in escapes, nor quotes and backslashes
+func parseSeparator(s string) (rune, error) {
+	if s == `\t` {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if s == "" || size != len(s) || r == utf8.RuneError {
+		return 0, fmt.Errorf("separator must be a single character, got: %q", s)
+	}
+	if (r != '\t' && r != ' ' && !unicode.IsPunct(r) && !unicode.IsSymbol(r)) || r == '"' || r == '\\' {
+		return 0, fmt.Errorf("separator must be a tab, space, punctuation or symbol other than a quote or backslash, got: %q", s)
+	}
+	return r, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTSVRoundTrip(t *testing.T) {
+	records := [][]string{
+		{"IMAGE", "FILE", "ERROR"},
+		{"alpine:latest", `C:\app\lib.jar`, "unable to read:\n\tbad header\r\n"},
+		{"example/app:1", "/opt/a|b,c", `trailing backslash \`},
+		{"", "", ""},
+	}
+	for _, separator := range []rune{'\t', '|', ','} {
+		var out strings.Builder
+		w := &tsvResultWriter{w: &out, separator: separator}
+		for _, record := range records {
+			if err := w.writeRecord(record); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if lines := strings.Count(out.String(), "\n"); lines != len(records) {
+			t.Errorf("separator %q: wrote %d lines, want one per record:\n%s", separator, lines, out.String())
+		}
+
+		r := bufio.NewReader(strings.NewReader(out.String()))
+		if got := headerSeparator(r, ','); got != separator {
+			t.Errorf("headerSeparator() = %q, want %q", got, separator)
+		}
+		reader := &tsvReader{r: r, separator: separator}
+		for _, want := range records {
+			got, err := reader.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("separator %q: read %q, want %q", separator, got, want)
+			}
+		}
+		if _, err := reader.Read(); !errors.Is(err, io.EOF) {
+			t.Errorf("separator %q: read past the end = %v, want EOF", separator, err)
+		}
+	}
+}
+
+func TestParseSeparator(t *testing.T) {
+	tests := []struct {
+		input string
+		want  rune
+	}{
+		{input: `\t`, want: '\t'},
+		{input: "\t", want: '\t'},
+		{input: "|", want: '|'},
+		{input: ";", want: ';'},
+		{input: " ", want: ' '},
+		{input: ""},
+		{input: "ab"},
+		{input: "x"},
+		{input: "1"},
+		{input: `"`},
+		{input: `\`},
+	}
+	for _, test := range tests {
+		got, err := parseSeparator(test.input)
+		if test.want == 0 {
+			if err == nil {
+				t.Errorf("parseSeparator(%q) = %q, want an error", test.input, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("parseSeparator(%q) = %q, %v, want %q", test.input, got, err, test.want)
+		}
+	}
+}